@@ -0,0 +1,31 @@
+package bitmask
+
+import "sync/atomic"
+
+// AtomicKeySet is a KeySet that is safe for concurrent use
+// The zero value is an empty set
+type AtomicKeySet struct {
+	v uint32
+}
+
+// Load returns the current keys
+func (a *AtomicKeySet) Load() KeySet {
+	return KeySet(atomic.LoadUint32(&a.v))
+}
+
+// Store sets the current keys
+func (a *AtomicKeySet) Store(k KeySet) {
+	atomic.StoreUint32(&a.v, uint32(k))
+}
+
+// Apply adds the add keys and removes the remove keys in a single atomic step
+// and returns the new value. Keys in both add and remove end up removed.
+func (a *AtomicKeySet) Apply(add, remove KeySet) KeySet {
+	for {
+		old := atomic.LoadUint32(&a.v)
+		val := (KeySet(old) | add) &^ remove
+		if atomic.CompareAndSwapUint32(&a.v, old, uint32(val)) {
+			return val
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package bitmask
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestAtomicApply(t *testing.T) {
+	var a AtomicKeySet
+	a.Store(Copper | Crystal)
+
+	if k := a.Apply(Jade, Copper); k != Jade|Crystal {
+		t.Fatalf("apply: %q", k)
+	}
+
+	if k := a.Load(); k != Jade|Crystal {
+		t.Fatalf("load: %q", k)
+	}
+}
+
+func TestAtomicApplyConcurrent(t *testing.T) {
+	var a AtomicKeySet
+	a.Store(Copper)
+
+	// Trades swap copper for jade and back, a player always has exactly one
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				if i%2 == 0 {
+					a.Apply(Jade, Copper)
+				} else {
+					a.Apply(Copper, Jade)
+				}
+			}
+		}(i)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	for {
+		select {
+		case <-done:
+			return
+		default:
+		}
+
+		if k := a.Load(); k != Copper && k != Jade {
+			t.Fatalf("half applied trade: %q", k)
+		}
+	}
+}
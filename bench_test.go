@@ -65,3 +65,66 @@ func BenchmarkMemory(b *testing.B) {
 
 	}
 }
+
+func BenchmarkNames(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if len(bKeys.Names()) != 2 {
+			b.Fatal()
+		}
+	}
+}
+
+func BenchmarkAppendNames(b *testing.B) {
+	b.ReportAllocs()
+	buf := make([]string, 0, 8)
+	for i := 0; i < b.N; i++ {
+		buf = bKeys.AppendNames(buf[:0])
+		if len(buf) != 2 {
+			b.Fatal()
+		}
+	}
+}
+
+func hasAllLoop(p *Player, mask KeySet) bool {
+	for key := Copper; key < maxKey; key <<= 1 {
+		if mask&key != 0 && !p.HasKey(key) {
+			return false
+		}
+	}
+	return true
+}
+
+func BenchmarkHasKeyLoop(b *testing.B) {
+	p := Player{Name: "Parzival", Keys: All()}
+	for i := 0; i < b.N; i++ {
+		if !hasAllLoop(&p, Copper|Crystal) {
+			b.Fatal()
+		}
+	}
+}
+
+func BenchmarkHasAllFast(b *testing.B) {
+	p := Player{Name: "Parzival", Keys: All()}
+	for i := 0; i < b.N; i++ {
+		if !p.Keys.HasAllFast(Copper | Crystal) {
+			b.Fatal()
+		}
+	}
+}
+
+func BenchmarkString(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		if bKeys.String() != "copper|crystal" {
+			b.Fatal()
+		}
+	}
+}
+
+func BenchmarkStringNoCache(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		if bKeys.format() != "copper|crystal" {
+			b.Fatal()
+		}
+	}
+}
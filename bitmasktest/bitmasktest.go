@@ -0,0 +1,28 @@
+// Package bitmasktest has test helpers for bitmask.KeySet
+package bitmasktest
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/353words/bitmask"
+)
+
+// TB is the part of testing.TB used by the helpers, *testing.T implements it
+type TB interface {
+	Helper()
+	Fatalf(format string, args ...any)
+}
+
+// RequireKeys fails the test if got is not want
+func RequireKeys(t TB, got, want bitmask.KeySet) {
+	t.Helper()
+	if got != want {
+		t.Fatalf("keys: got %s, want %s", format(got), format(want))
+	}
+}
+
+// format returns the names and the hex value of k, e.g. [copper jade] (0x03)
+func format(k bitmask.KeySet) string {
+	return fmt.Sprintf("[%s] (%#02x)", strings.Join(k.Names(), " "), uint8(k))
+}
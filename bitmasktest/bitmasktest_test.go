@@ -0,0 +1,39 @@
+package bitmasktest
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/353words/bitmask"
+)
+
+type fakeT struct {
+	failed bool
+	msg    string
+}
+
+func (t *fakeT) Helper() {}
+
+func (t *fakeT) Fatalf(format string, args ...any) {
+	t.failed = true
+	t.msg = fmt.Sprintf(format, args...)
+}
+
+func TestRequireKeys(t *testing.T) {
+	var ft fakeT
+	RequireKeys(&ft, bitmask.Copper|bitmask.Jade, bitmask.Jade|bitmask.Copper)
+	if ft.failed {
+		t.Fatalf("failed on equal keys: %s", ft.msg)
+	}
+
+	RequireKeys(&ft, bitmask.Copper|bitmask.Jade, bitmask.Crystal)
+	if !ft.failed {
+		t.Fatalf("not failed on different keys")
+	}
+	expected := "keys: got [copper jade] (0x03), want [crystal] (0x04)"
+	if ft.msg != expected {
+		t.Fatalf("message: %q != %q", ft.msg, expected)
+	}
+
+	RequireKeys(t, bitmask.All(), bitmask.Copper|bitmask.Jade|bitmask.Crystal)
+}
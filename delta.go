@@ -0,0 +1,50 @@
+package bitmask
+
+import (
+	"encoding/csv"
+	"io"
+	"time"
+)
+
+// DeltaEntry is a change in keys
+type DeltaEntry struct {
+	Time   time.Time
+	Gained KeySet
+	Lost   KeySet
+}
+
+// DeltaLog is a log of key changes
+type DeltaLog struct {
+	entries []DeltaEntry
+}
+
+// Record records the change from before to after at time at
+func (l *DeltaLog) Record(before, after KeySet, at time.Time) {
+	gained, lost := before.Diff(after)
+	l.entries = append(l.entries, DeltaEntry{Time: at, Gained: gained, Lost: lost})
+}
+
+// Entries returns the log entries, oldest first
+func (l *DeltaLog) Entries() []DeltaEntry {
+	entries := make([]DeltaEntry, len(l.entries))
+	copy(entries, l.entries)
+	return entries
+}
+
+// WriteCSV writes the log entries as CSV with time, gained and lost columns
+func (l *DeltaLog) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"time", "gained", "lost"}); err != nil {
+		return err
+	}
+
+	for _, e := range l.entries {
+		row := []string{e.Time.Format(time.RFC3339), e.Gained.String(), e.Lost.String()}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,34 @@
+package bitmask
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestDeltaLog(t *testing.T) {
+	start := time.Date(2026, time.October, 15, 10, 0, 0, 0, time.UTC)
+	var l DeltaLog
+	l.Record(0, Copper, start)
+	l.Record(Copper, Jade|Crystal, start.Add(time.Minute))
+
+	entries := l.Entries()
+	if len(entries) != 2 {
+		t.Fatalf("entries: %v", entries)
+	}
+	e := entries[1]
+	if e.Gained != Jade|Crystal || e.Lost != Copper || !e.Time.Equal(start.Add(time.Minute)) {
+		t.Fatalf("entry: %+v", e)
+	}
+
+	var buf bytes.Buffer
+	if err := l.WriteCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	expected := "time,gained,lost\n" +
+		"2026-10-15T10:00:00Z,copper,\n" +
+		"2026-10-15T10:01:00Z,jade|crystal,copper\n"
+	if buf.String() != expected {
+		t.Fatalf("csv:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,46 @@
+package bitmask
+
+// MinKeysForDoors returns the keys a player with have needs to open all doors
+func MinKeysForDoors(have KeySet, doors []KeySet) KeySet {
+	return CombineAll(doors) &^ have
+}
+
+// MinimalKeysFor returns the keys needed to open all doors
+func MinimalKeysFor(doors []KeySet) KeySet {
+	return MinKeysForDoors(0, doors)
+}
+
+// CanOpenAll returns true if have has the required keys for every door
+func CanOpenAll(have KeySet, doors []KeySet) bool {
+	for _, door := range doors {
+		if !door.IsSubsetOf(have) {
+			return false
+		}
+	}
+	return true
+}
+
+// BestNextKey returns the missing key that opens the most doors that can't be
+// opened with have, and the number of these doors.
+// Ties go to the lower key, it returns an empty set and 0 if no single key
+// opens a new door.
+func BestNextKey(have KeySet, doors []KeySet) (KeySet, int) {
+	var best KeySet
+	bestCount := 0
+	for _, key := range definedKeys {
+		if have&key != 0 {
+			continue
+		}
+
+		count := 0
+		for _, door := range doors {
+			if !door.IsSubsetOf(have) && door.IsSubsetOf(have|key) {
+				count++
+			}
+		}
+		if count > bestCount {
+			best, bestCount = key, count
+		}
+	}
+	return best, bestCount
+}
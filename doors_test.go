@@ -0,0 +1,64 @@
+package bitmask
+
+import "testing"
+
+func TestMinKeysForDoors(t *testing.T) {
+	doors := []KeySet{Copper | Jade, Jade | Crystal, Jade}
+
+	if k := MinKeysForDoors(Jade, doors); k != Copper|Crystal {
+		t.Fatalf("jade: %q", k)
+	}
+
+	if k := MinKeysForDoors(All(), doors); k != 0 {
+		t.Fatalf("all: %q", k)
+	}
+
+	if k := MinKeysForDoors(0, nil); k != 0 {
+		t.Fatalf("no doors: %q", k)
+	}
+}
+
+func TestMinimalKeysFor(t *testing.T) {
+	shared := []KeySet{Copper | Jade, Jade}
+	if k := MinimalKeysFor(shared); k != Copper|Jade {
+		t.Fatalf("shared: %q", k)
+	}
+	if !CanOpenAll(Copper|Jade, shared) {
+		t.Fatalf("copper|jade can't open %v", shared)
+	}
+	if CanOpenAll(Jade, shared) {
+		t.Fatalf("jade can open %v", shared)
+	}
+
+	disjoint := []KeySet{Copper, Crystal}
+	if k := MinimalKeysFor(disjoint); k != Copper|Crystal {
+		t.Fatalf("disjoint: %q", k)
+	}
+	if CanOpenAll(Copper|Jade, disjoint) {
+		t.Fatalf("copper|jade can open %v", disjoint)
+	}
+
+	if !CanOpenAll(0, nil) {
+		t.Fatalf("can't open no doors")
+	}
+}
+
+func TestBestNextKey(t *testing.T) {
+	doors := []KeySet{Copper | Jade, Crystal, Jade, Copper | Crystal}
+
+	key, n := BestNextKey(Copper, doors)
+	if key != Jade || n != 2 {
+		t.Fatalf("copper: %q, %d", key, n)
+	}
+
+	// jade and crystal open one door each, jade is the lower key
+	key, n = BestNextKey(0, []KeySet{Crystal, Jade})
+	if key != Jade || n != 1 {
+		t.Fatalf("tie: %q, %d", key, n)
+	}
+
+	key, n = BestNextKey(0, []KeySet{Copper | Jade})
+	if key != 0 || n != 0 {
+		t.Fatalf("no single key: %q, %d", key, n)
+	}
+}
@@ -0,0 +1,50 @@
+package bitmask
+
+// PlayerFlags packs a KeySet and boolean game flags in two bytes
+// The low byte holds the keys, the high byte holds the flags
+type PlayerFlags uint16
+
+const keysMask PlayerFlags = 0xFF
+
+const (
+	hasMapFlag  PlayerFlags = 1 << (8 + iota) // 256
+	isGhostFlag                               // 512
+)
+
+// Keys returns the player keys
+func (f PlayerFlags) Keys() KeySet {
+	return KeySet(f & keysMask)
+}
+
+// SetKeys sets the player keys, flags are not changed
+func (f *PlayerFlags) SetKeys(k KeySet) {
+	*f = *f&^keysMask | PlayerFlags(k)
+}
+
+// HasMap returns true if the player has the map
+func (f PlayerFlags) HasMap() bool {
+	return f&hasMapFlag != 0
+}
+
+// SetHasMap sets if the player has the map
+func (f *PlayerFlags) SetHasMap(v bool) {
+	f.set(hasMapFlag, v)
+}
+
+// IsGhost returns true if the player is a ghost
+func (f PlayerFlags) IsGhost() bool {
+	return f&isGhostFlag != 0
+}
+
+// SetIsGhost sets if the player is a ghost
+func (f *PlayerFlags) SetIsGhost(v bool) {
+	f.set(isGhostFlag, v)
+}
+
+func (f *PlayerFlags) set(flag PlayerFlags, v bool) {
+	if v {
+		*f |= flag
+	} else {
+		*f &= ^flag
+	}
+}
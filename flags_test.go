@@ -0,0 +1,30 @@
+package bitmask
+
+import "testing"
+
+func TestPlayerFlags(t *testing.T) {
+	var f PlayerFlags
+	f.SetKeys(Copper | Crystal)
+	f.SetHasMap(true)
+	f.SetIsGhost(true)
+
+	if k := f.Keys(); k != Copper|Crystal {
+		t.Fatalf("keys after flags: %q", k)
+	}
+	if !f.HasMap() || !f.IsGhost() {
+		t.Fatalf("flags not set: %016b", f)
+	}
+
+	f.SetHasMap(false)
+	if f.HasMap() || !f.IsGhost() {
+		t.Fatalf("flags after clear: %016b", f)
+	}
+
+	f.SetKeys(Jade)
+	if k := f.Keys(); k != Jade {
+		t.Fatalf("keys after set: %q", k)
+	}
+	if !f.IsGhost() {
+		t.Fatalf("flags after set keys: %016b", f)
+	}
+}
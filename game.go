@@ -2,7 +2,14 @@ package bitmask
 
 import (
 	"fmt"
+	"math/bits"
+	"math/rand"
+	"os"
+	"sort"
 	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
 )
 
 // KeySet is a set of keys in the game
@@ -15,44 +22,203 @@ const (
 	maxKey
 )
 
+// validKeys has all defined keys set
+const validKeys = maxKey - 1
+
+// keyNames are the key names indexed by bit position, update when adding a key
+var keyNames = []string{"copper", "jade", "crystal"}
+
+var (
+	definedKeys []KeySet          // defined keys in bit order, built from keyNames
+	nameToKey   map[string]KeySet // key name to key, built from keyNames
+)
+
+func init() {
+	if err := checkKeys(keyNames); err != nil {
+		panic(err)
+	}
+
+	definedKeys = make([]KeySet, len(keyNames))
+	nameToKey = make(map[string]KeySet, len(keyNames))
+	for i, name := range keyNames {
+		definedKeys[i] = 1 << i
+		nameToKey[name] = 1 << i
+	}
+}
+
+// checkKeys checks that there's a name for every key constant. A constant
+// without "1 << iota" or a missing name makes maxKey disagree with names.
+func checkKeys(names []string) error {
+	if next := KeySet(1 << len(names)); next != maxKey {
+		return fmt.Errorf("%d key names, but maxKey is %d (should be %d)", len(names), maxKey, next)
+	}
+	return nil
+}
+
+var (
+	keyStrings     [256]string // String of every KeySet value
+	keyStringsOnce sync.Once
+)
+
 // String implements the fmt.Stringer interface
 func (k KeySet) String() string {
+	keyStringsOnce.Do(func() {
+		for i := range keyStrings {
+			keyStrings[i] = KeySet(i).format()
+		}
+	})
+	return keyStrings[k]
+}
+
+// format returns the string representation of k, String caches it
+func (k KeySet) format() string {
 	if k >= maxKey {
 		return fmt.Sprintf("<unknown key: %d>", k)
 	}
 
-	switch k {
-	case Copper:
-		return "copper"
-	case Jade:
-		return "jade"
-	case Crystal:
-		return "crystal"
+	if k.IsSingle() {
+		i, _ := k.BitIndex()
+		return keyNames[i]
 	}
 
 	// multiple keys
-	var names []string
-	for key := Copper; key < maxKey; key <<= 1 {
-		if k&key != 0 {
-			names = append(names, key.String())
+	return strings.Join(k.names(), "|")
+}
+
+// ParseKeySet parses the output of String, e.g. "copper|jade"
+func ParseKeySet(s string) (KeySet, error) {
+	if s == "" {
+		return 0, nil
+	}
+
+	var keys KeySet
+	for _, name := range strings.Split(s, "|") {
+		key, ok := keyByName(name)
+		if !ok {
+			return 0, fmt.Errorf("unknown key: %q", name)
 		}
+		keys |= key
 	}
-	return strings.Join(names, "|")
+	return keys, nil
+}
+
+// ParseKeySetArgs parses a key name from every arg, e.g. from os.Args
+func ParseKeySetArgs(args []string) (KeySet, error) {
+	var keys KeySet
+	for _, name := range args {
+		key, ok := keyByName(name)
+		if !ok {
+			return 0, fmt.Errorf("unknown key: %q", name)
+		}
+		keys |= key
+	}
+	return keys, nil
+}
+
+// OnDecodeError is called by DecodeOrDefault with the parse error
+var OnDecodeError func(error)
+
+// DecodeOrDefault parses s with ParseKeySet and returns def if s is invalid
+func DecodeOrDefault(s string, def KeySet) KeySet {
+	k, err := ParseKeySet(s)
+	if err != nil {
+		if OnDecodeError != nil {
+			OnDecodeError(err)
+		}
+		return def
+	}
+	return k
+}
+
+// KeySetFromEnv parses the environment variable name with ParseKeySet
+// An unset or empty variable is an empty KeySet
+func KeySetFromEnv(name string) (KeySet, error) {
+	return ParseKeySet(strings.TrimSpace(os.Getenv(name)))
+}
+
+// names returns the names of the defined keys in k
+func (k KeySet) names() []string {
+	names := []string{}
+	for i, name := range keyNames {
+		if k&(1<<i) != 0 {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// aliases are alternate key names, see RegisterAlias
+var aliases = map[string]KeySet{}
+
+// RegisterAlias registers alias as another name for the single key k
+// ParseKeySet accepts aliases, String still returns the key name. It should
+// be called at program startup.
+func RegisterAlias(alias string, k KeySet) error {
+	if !k.IsSingle() || !k.IsValid() {
+		return fmt.Errorf("%d is not a single key", k)
+	}
+	if _, ok := nameToKey[alias]; ok {
+		return fmt.Errorf("%q is a key name", alias)
+	}
+
+	aliases[alias] = k
+	return nil
+}
+
+// keyByName returns the key with name or alias
+func keyByName(name string) (KeySet, bool) {
+	if key, ok := nameToKey[name]; ok {
+		return key, true
+	}
+	key, ok := aliases[name]
+	return key, ok
 }
 
 // Player is a player in the game
 type Player struct {
 	Name string
 	Keys KeySet
+	Seen KeySet `json:"-"` // keys the player ever had, not serialized
 }
 
 // AddKey adds a key to the player keys
 func (p *Player) AddKey(key KeySet) {
 	p.Keys |= key
+	p.Seen |= key
 }
 
+// AddKeyWithin adds the keys in key that are in allowed
+// It returns an error with the keys that are not allowed
+func (p *Player) AddKeyWithin(allowed, key KeySet) error {
+	p.AddKey(key & allowed)
+	if denied := key &^ allowed; denied != 0 {
+		return fmt.Errorf("keys not allowed: %s", denied)
+	}
+	return nil
+}
+
+// FirstTime returns true if the player never had key and marks it as seen
+// Call it before AddKey to tell if this is the first time the key is found
+func (p *Player) FirstTime(key KeySet) bool {
+	if p.Seen&key == key {
+		return false
+	}
+	p.Seen |= key
+	return true
+}
+
+// StrictHasKey makes HasKey require all keys in a multi key argument
+// The default (false) is to require any of the keys. This will change to true
+// in a future release, set it to true and fix call sites that pass several
+// keys and expect any of them to match.
+var StrictHasKey = false
+
 // HasKey returns true if player has a key
+// See StrictHasKey for keys with more than one key
 func (p *Player) HasKey(key KeySet) bool {
+	if StrictHasKey {
+		return p.Keys&key == key
+	}
 	return p.Keys&key != 0
 }
 
@@ -60,3 +226,570 @@ func (p *Player) HasKey(key KeySet) bool {
 func (p *Player) RemoveKey(key KeySet) {
 	p.Keys &= ^key
 }
+
+// OnKeysChange is called by Player.Update when the player keys change
+var OnKeysChange func(p *Player, before, after KeySet)
+
+// Update calls every function in fns with p, then calls OnKeysChange once if
+// the player keys changed
+func (p *Player) Update(fns ...func(*Player)) {
+	before := p.Keys
+	for _, fn := range fns {
+		fn(p)
+	}
+
+	if OnKeysChange != nil && p.Keys != before {
+		OnKeysChange(p, before, p.Keys)
+	}
+}
+
+// GrantAll gives the player all keys
+func (p *Player) GrantAll() {
+	p.Keys = All()
+	p.Seen |= All()
+}
+
+// RevokeAll removes all keys from player
+func (p *Player) RevokeAll() {
+	p.Keys = 0
+}
+
+// Equal returns true if p and o have the same name and keys
+func (p Player) Equal(o Player) bool {
+	return p.Name == o.Name && p.Keys == o.Keys
+}
+
+// HashKey returns a string that can be used as a map key
+// Players that are Equal have the same HashKey
+func (p Player) HashKey() string {
+	return fmt.Sprintf("%s:%02x", p.Name, byte(p.Keys))
+}
+
+// ToSliceByPriority returns the keys in k ordered by order
+// Keys in k that are not in order are appended in bit order
+func (k KeySet) ToSliceByPriority(order []KeySet) []KeySet {
+	var keys []KeySet
+	var added KeySet
+	for _, key := range order {
+		if !key.IsSingle() {
+			continue
+		}
+		if k&key != 0 && added&key == 0 {
+			keys = append(keys, key)
+			added |= key
+		}
+	}
+
+	for key := Copper; key < maxKey; key <<= 1 {
+		if k&key != 0 && added&key == 0 {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// StepsTo returns the key sets from k to target, adding one key at a time
+// starting from the lowest key. Keys in k that are not in target are kept.
+func (k KeySet) StepsTo(target KeySet) []KeySet {
+	var steps []KeySet
+	missing := target &^ k
+	for key := KeySet(1); key != 0; key <<= 1 {
+		if missing&key != 0 {
+			k |= key
+			steps = append(steps, k)
+		}
+	}
+	return steps
+}
+
+// Intersects returns true if k and o have a key in common
+func (k KeySet) Intersects(o KeySet) bool {
+	return k&o != 0
+}
+
+// Overlaps returns true if k and o have a key in common, same as Intersects
+func (k KeySet) Overlaps(o KeySet) bool {
+	return k.Intersects(o)
+}
+
+// OverlapMatrix returns a matrix where [i][j] is true if sets[i] and sets[j]
+// have a key in common
+func OverlapMatrix(sets []KeySet) [][]bool {
+	matrix := make([][]bool, len(sets))
+	for i, a := range sets {
+		matrix[i] = make([]bool, len(sets))
+		for j, b := range sets {
+			matrix[i][j] = a.Intersects(b)
+		}
+	}
+	return matrix
+}
+
+// CommonKeys returns the keys all players have
+func CommonKeys(players []*Player) KeySet {
+	if len(players) == 0 {
+		return 0
+	}
+
+	keys := players[0].Keys
+	for _, p := range players[1:] {
+		keys &= p.Keys
+	}
+	return keys
+}
+
+// KeyHandlers maps a single key to a handler
+type KeyHandlers map[KeySet]func()
+
+// Dispatch calls the handler of every key in k, keys without a handler are ignored
+func (h KeyHandlers) Dispatch(k KeySet) {
+	for key := Copper; key < maxKey; key <<= 1 {
+		if k&key == 0 {
+			continue
+		}
+		if fn, ok := h[key]; ok {
+			fn()
+		}
+	}
+}
+
+// CombinedKeys returns the keys any of the players have
+func CombinedKeys(players []*Player) KeySet {
+	var keys KeySet
+	for _, p := range players {
+		keys |= p.Keys
+	}
+	return keys
+}
+
+// StringN is like String but lists at most n keys
+func (k KeySet) StringN(n int) string {
+	if k >= maxKey {
+		return k.String()
+	}
+
+	names := k.names()
+	if n < 0 {
+		n = 0
+	}
+	if len(names) <= n {
+		return k.String()
+	}
+	return fmt.Sprintf("%s…(+%d more)", strings.Join(names[:n], "|"), len(names)-n)
+}
+
+// IsValid returns true if k has only defined keys
+func (k KeySet) IsValid() bool {
+	return k&^validKeys == 0
+}
+
+// UnknownBits returns the bits in k that are not defined keys
+func (k KeySet) UnknownBits() KeySet {
+	return k &^ validKeys
+}
+
+// TrimUnknown returns k without the unknown bits and true if there were any
+func (k KeySet) TrimUnknown() (clean KeySet, hadUnknown bool) {
+	return k & validKeys, !k.IsValid()
+}
+
+// All returns a KeySet with all defined keys
+func All() KeySet {
+	return validKeys
+}
+
+// RandomKeySet returns a random set of defined keys
+func RandomKeySet(r *rand.Rand) KeySet {
+	return KeySet(r.Intn(int(All()) + 1))
+}
+
+// Missing returns the keys in required that are not in k
+func (k KeySet) Missing(required KeySet) KeySet {
+	return required &^ k
+}
+
+// Count returns the number of keys in k
+func (k KeySet) Count() int {
+	return bits.OnesCount8(uint8(k))
+}
+
+// Score returns the sum of weights of the keys in k
+// Keys missing from weights have a weight of 0
+func (k KeySet) Score(weights map[KeySet]int) int {
+	score := 0
+	for key := KeySet(1); key != 0; key <<= 1 {
+		if k&key != 0 {
+			score += weights[key]
+		}
+	}
+	return score
+}
+
+// Less returns true if k sorts before o, by numeric value
+// The empty set sorts first
+func (k KeySet) Less(o KeySet) bool {
+	return k < o
+}
+
+// RankByKeys returns the rank of each player by number of keys, most keys first
+// Players with the same number of keys share a rank and the next rank is
+// skipped (e.g. 1, 2, 2, 4)
+func RankByKeys(players []Player) map[string]int {
+	counts := make([]int, len(players))
+	for i, p := range players {
+		counts[i] = p.Keys.Count()
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(counts)))
+
+	ranks := make(map[string]int, len(players))
+	for _, p := range players {
+		n := p.Keys.Count()
+		// rank is one more than the number of players with more keys
+		ranks[p.Name] = sort.Search(len(counts), func(i int) bool { return counts[i] <= n }) + 1
+	}
+	return ranks
+}
+
+// IsSubsetOf returns true if all keys in k are in o
+func (k KeySet) IsSubsetOf(o KeySet) bool {
+	return k&^o == 0
+}
+
+// Diff returns the keys added and removed going from k to to
+func (k KeySet) Diff(to KeySet) (added, removed KeySet) {
+	return to &^ k, k &^ to
+}
+
+// DiffString returns the keys added and removed going from a to b
+// e.g. "+crystal -copper", it returns an empty string if there's no change
+func DiffString(a, b KeySet) string {
+	added, removed := a.Diff(b)
+	var parts []string
+	for _, name := range added.Names() {
+		parts = append(parts, "+"+name)
+	}
+	for _, name := range removed.Names() {
+		parts = append(parts, "-"+name)
+	}
+	return strings.Join(parts, " ")
+}
+
+// Select returns a if cond is true, b otherwise
+// It uses a bit mask instead of a branch on cond, to avoid timing side channels
+// when cond is secret. Like crypto/subtle this is best effort, the Go compiler
+// does not guarantee constant time.
+func Select(cond bool, a, b KeySet) KeySet {
+	mask := -b2i(cond) // all ones if cond, zero otherwise
+	return b ^ ((a ^ b) & mask)
+}
+
+// b2i returns 1 for true and 0 for false
+// Current compilers make it branch free, like Select this is best effort.
+func b2i(b bool) KeySet {
+	var i KeySet
+	if b {
+		i = 1
+	}
+	return i
+}
+
+// GoString implements the fmt.GoStringer interface, it's used by %#v
+// e.g. bitmask.Copper|bitmask.Jade
+func (k KeySet) GoString() string {
+	var parts []string
+	for _, key := range definedKeys {
+		if k&key != 0 {
+			name := key.String()
+			parts = append(parts, "bitmask."+strings.ToUpper(name[:1])+name[1:])
+		}
+	}
+
+	if u := k.UnknownBits(); u != 0 || k == 0 {
+		parts = append(parts, fmt.Sprintf("bitmask.KeySet(%#x)", uint8(u)))
+	}
+	return strings.Join(parts, "|")
+}
+
+// Partition splits k to the keys inside mask and the keys outside of it
+func (k KeySet) Partition(mask KeySet) (inside, outside KeySet) {
+	return k & mask, k &^ mask
+}
+
+// KeyOfDay returns the key of the day for date
+func KeyOfDay(date time.Time) KeySet {
+	return definedKeys[date.YearDay()%len(definedKeys)]
+}
+
+// Names returns the names of the keys in k
+// Unknown bits are named "<unknown bit N>"
+func (k KeySet) Names() []string {
+	return k.AppendNames(nil)
+}
+
+// AppendNames appends the names of the keys in k to dst and returns the
+// extended slice, see Names
+func (k KeySet) AppendNames(dst []string) []string {
+	for i := 0; i < 8; i++ {
+		key := KeySet(1 << i)
+		if k&key == 0 {
+			continue
+		}
+		if key < maxKey {
+			dst = append(dst, key.String())
+		} else {
+			dst = append(dst, fmt.Sprintf("<unknown bit %d>", i))
+		}
+	}
+	return dst
+}
+
+// NextSuggested returns the first key in priority the player doesn't have
+// It returns an empty set if the player has all keys in priority
+func (p *Player) NextSuggested(priority []KeySet) KeySet {
+	for _, key := range priority {
+		if m := p.Keys.Missing(key); m != 0 {
+			return m
+		}
+	}
+	return 0
+}
+
+// InRange returns true if the numeric value of k is between low and high,
+// inclusive
+func (k KeySet) InRange(low, high KeySet) bool {
+	return low <= k && k <= high
+}
+
+// checksumSalt is mixed into the checksum so it's not the key value
+const checksumSalt = 0xA5
+
+// Checksum returns a checksum of k for detecting casual changes in save files
+// It is NOT cryptographic, anyone reading this code can forge it.
+func (k KeySet) Checksum() byte {
+	// rotate and xor are reversible, every value has a different checksum
+	return bits.RotateLeft8(uint8(k), 3) ^ checksumSalt
+}
+
+// VerifyKeySet returns true if sum is the checksum of k
+func VerifyKeySet(k KeySet, sum byte) bool {
+	return k.Checksum() == sum
+}
+
+// EqualsAny returns true if k is equal to one of options
+func (k KeySet) EqualsAny(options ...KeySet) bool {
+	for _, o := range options {
+		if k == o {
+			return true
+		}
+	}
+	return false
+}
+
+// FilterNames returns the names of the keys in k that pred returns true for
+func (k KeySet) FilterNames(pred func(KeySet) bool) []string {
+	var names []string
+	for _, key := range definedKeys {
+		if k&key != 0 && pred(key) {
+			names = append(names, key.String())
+		}
+	}
+	return names
+}
+
+// DisabledKeys are keys disabled server wide (e.g. during an event), see Sanitize
+var DisabledKeys KeySet
+
+// MaskOut returns k without the blocked keys
+func (k KeySet) MaskOut(blocked KeySet) KeySet {
+	return k &^ blocked
+}
+
+// Sanitize returns k without DisabledKeys
+func (k KeySet) Sanitize() KeySet {
+	return k.MaskOut(DisabledKeys)
+}
+
+// PadString returns String padded with spaces to width runes, aligned to the
+// right if alignRight is true and to the left otherwise.
+// Longer strings are truncated and end with "…"
+func (k KeySet) PadString(width int, alignRight bool) string {
+	if width <= 0 {
+		return ""
+	}
+
+	s := k.String()
+	n := utf8.RuneCountInString(s)
+	if n > width {
+		return string([]rune(s)[:width-1]) + "…"
+	}
+
+	pad := strings.Repeat(" ", width-n)
+	if alignRight {
+		return pad + s
+	}
+	return s + pad
+}
+
+// Combine returns all keys in keys
+func Combine(keys ...KeySet) KeySet {
+	return CombineAll(keys)
+}
+
+// CombineAll returns all keys in keys
+func CombineAll(keys []KeySet) KeySet {
+	var all KeySet
+	for _, k := range keys {
+		all |= k
+	}
+	return all
+}
+
+// ToBools returns a map of every key name to whether it's in k
+func (k KeySet) ToBools() map[string]bool {
+	m := make(map[string]bool, len(keyNames))
+	for i, name := range keyNames {
+		m[name] = k&(1<<i) != 0
+	}
+	return m
+}
+
+// KeySetFromBools returns the keys that are true in m, see ToBools
+func KeySetFromBools(m map[string]bool) (KeySet, error) {
+	var keys KeySet
+	for name, ok := range m {
+		key, found := keyByName(name)
+		if !found {
+			return 0, fmt.Errorf("unknown key: %q", name)
+		}
+		if ok {
+			keys |= key
+		}
+	}
+	return keys, nil
+}
+
+// HasAllFast returns true if k has all keys in mask
+// Prefer it over checking the keys one by one, it's a single AND and compare
+func (k KeySet) HasAllFast(mask KeySet) bool {
+	return k&mask == mask
+}
+
+// MergeAuthoritative does a three way merge of local and remote keys that
+// were base in the last sync. Keys changed on one side are taken from that
+// side, keys changed on both sides are taken from remote (the server).
+func MergeAuthoritative(local, remote, base KeySet) KeySet {
+	localOnly := (local ^ base) &^ (remote ^ base)
+	return remote&^localOnly | local&localOnly
+}
+
+// IsSingle returns true if k has exactly one key
+func (k KeySet) IsSingle() bool {
+	return k != 0 && k&(k-1) == 0
+}
+
+// BitIndex returns the bit position of a single key and true
+// It returns false if k is empty or has more than one key
+func (k KeySet) BitIndex() (int, bool) {
+	if !k.IsSingle() {
+		return 0, false
+	}
+	return bits.TrailingZeros8(uint8(k)), true
+}
+
+// IterFrom calls fn with every key in k starting from start and stops when fn
+// returns false. start should be a single key, an empty start means from the
+// first key.
+func (k KeySet) IterFrom(start KeySet, fn func(KeySet) bool) {
+	if start == 0 {
+		start = 1
+	}
+
+	for key := start & -start; key != 0; key <<= 1 {
+		if k&key != 0 && !fn(key) {
+			return
+		}
+	}
+}
+
+// ComparePlayers returns a summary of the keys a and b have
+// e.g. "Parzival has copper; Art3mis has jade; both have crystal"
+func ComparePlayers(a, b *Player) string {
+	if a.Keys == b.Keys {
+		return "identical keyrings"
+	}
+
+	onlyB, onlyA := a.Keys.Diff(b.Keys)
+	both, _ := a.Keys.Partition(b.Keys)
+
+	var parts []string
+	if onlyA != 0 {
+		parts = append(parts, fmt.Sprintf("%s has %s", a.Name, onlyA))
+	}
+	if onlyB != 0 {
+		parts = append(parts, fmt.Sprintf("%s has %s", b.Name, onlyB))
+	}
+	if both != 0 {
+		parts = append(parts, fmt.Sprintf("both have %s", both))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// Next returns the key in k after current, wrapping around to the first key
+// It returns the first key if current is not a single key in k.
+func (k KeySet) Next(current KeySet) KeySet {
+	if k == 0 {
+		return 0
+	}
+
+	if current.IsSingle() && k&current != 0 {
+		for key := current << 1; key != 0; key <<= 1 {
+			if k&key != 0 {
+				return key
+			}
+		}
+	}
+	return k & -k // lowest key
+}
+
+// NextCombination returns the next set of defined keys after k, by numeric
+// value, and true. It returns false if k is All() or has unknown keys.
+// Starting from the empty set it goes over every combination of keys.
+func (k KeySet) NextCombination() (KeySet, bool) {
+	if !k.IsValid() {
+		return 0, false
+	}
+
+	for n := int(k) + 1; n <= int(All()); n++ {
+		if next := KeySet(n); next.IsValid() {
+			return next, true
+		}
+	}
+	return 0, false
+}
+
+// ID returns the value of k as two hex digits (e.g. "05"), it does not change
+// when key names change
+func (k KeySet) ID() string {
+	return fmt.Sprintf("%02x", uint8(k))
+}
+
+// Describe returns a sentence describing k, e.g. "holds the copper and jade keys"
+func (k KeySet) Describe() string {
+	names := k.names()
+	switch len(names) {
+	case 0:
+		return "holds no keys"
+	case 1:
+		return fmt.Sprintf("holds the %s key", names[0])
+	case 2:
+		return fmt.Sprintf("holds the %s and %s keys", names[0], names[1])
+	}
+
+	last := len(names) - 1
+	return fmt.Sprintf("holds the %s, and %s keys", strings.Join(names[:last], ", "), names[last])
+}
+
+// SharedKeyCount returns the number of keys both a and b have
+func SharedKeyCount(a, b *Player) int {
+	return (a.Keys & b.Keys).Count()
+}
@@ -1,9 +1,16 @@
 package bitmask
 
-import "testing"
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
 
 func TestKeys(t *testing.T) {
-	p := Player{"Parzival", 0}
+	p := Player{Name: "Parzival"}
 	if p.Keys.String() != "" {
 		t.Fatalf("empty keys: %q", p.Keys)
 	}
@@ -31,3 +38,1029 @@ func TestKeys(t *testing.T) {
 		t.Fatalf("jade not in %q", p.Keys)
 	}
 }
+
+func TestPlayerEqual(t *testing.T) {
+	p1 := Player{Name: "Parzival", Keys: Copper | Jade}
+	p2 := Player{Name: "Parzival", Keys: Jade | Copper}
+	if !p1.Equal(p2) {
+		t.Fatalf("%v != %v", p1, p2)
+	}
+	if p1.HashKey() != p2.HashKey() {
+		t.Fatalf("hash: %q != %q", p1.HashKey(), p2.HashKey())
+	}
+
+	others := []Player{
+		{Name: "Parzival", Keys: Copper},
+		{Name: "Art3mis", Keys: Copper | Jade},
+	}
+	for _, o := range others {
+		if p1.Equal(o) {
+			t.Fatalf("%v == %v", p1, o)
+		}
+		if p1.HashKey() == o.HashKey() {
+			t.Fatalf("hash: %v and %v are %q", p1, o, p1.HashKey())
+		}
+	}
+}
+
+func TestToSliceByPriority(t *testing.T) {
+	k := Copper | Jade | Crystal
+	keys := k.ToSliceByPriority([]KeySet{Crystal, Copper})
+	expected := []KeySet{Crystal, Copper, Jade}
+	if len(keys) != len(expected) {
+		t.Fatalf("%v != %v", keys, expected)
+	}
+	for i := range keys {
+		if keys[i] != expected[i] {
+			t.Fatalf("%v != %v", keys, expected)
+		}
+	}
+
+	keys = Jade.ToSliceByPriority([]KeySet{Crystal, Copper})
+	if len(keys) != 1 || keys[0] != Jade {
+		t.Fatalf("jade: %v", keys)
+	}
+}
+
+func TestStepsTo(t *testing.T) {
+	steps := Jade.StepsTo(Copper | Jade | Crystal)
+	expected := []KeySet{Copper | Jade, Copper | Jade | Crystal}
+	if len(steps) != len(expected) {
+		t.Fatalf("%v != %v", steps, expected)
+	}
+	for i := range steps {
+		if steps[i] != expected[i] {
+			t.Fatalf("%v != %v", steps, expected)
+		}
+	}
+
+	if steps := (Copper | Jade).StepsTo(Jade); len(steps) != 0 {
+		t.Fatalf("subset: %v", steps)
+	}
+}
+
+// expectedNames are the names of all defined keys, update when adding a key
+var expectedNames = map[KeySet]string{
+	Copper:  "copper",
+	Jade:    "jade",
+	Crystal: "crystal",
+}
+
+func TestKeyNames(t *testing.T) {
+	for key := Copper; key < maxKey; key <<= 1 {
+		name, ok := expectedNames[key]
+		if !ok {
+			t.Fatalf("key %d missing from expectedNames", key)
+		}
+		if key.String() != name {
+			t.Fatalf("key %d: String() = %q, expected %q", key, key, name)
+		}
+	}
+
+	for key, name := range expectedNames {
+		if key >= maxKey {
+			t.Fatalf("%q (%d) is not a defined key", name, key)
+		}
+	}
+}
+
+func TestOverlapMatrix(t *testing.T) {
+	sets := []KeySet{Copper | Jade, Jade, Crystal, 0}
+	expected := [][]bool{
+		{true, true, false, false},
+		{true, true, false, false},
+		{false, false, true, false},
+		{false, false, false, false},
+	}
+
+	matrix := OverlapMatrix(sets)
+	if len(matrix) != len(expected) {
+		t.Fatalf("%v != %v", matrix, expected)
+	}
+	for i := range expected {
+		for j := range expected[i] {
+			if matrix[i][j] != expected[i][j] {
+				t.Fatalf("[%d][%d] (%q, %q): %v", i, j, sets[i], sets[j], matrix[i][j])
+			}
+		}
+	}
+}
+
+func TestFirstTime(t *testing.T) {
+	p := Player{Name: "Parzival"}
+	if !p.FirstTime(Copper) {
+		t.Fatalf("copper not first time")
+	}
+	p.AddKey(Copper)
+
+	p.RemoveKey(Copper)
+	if p.FirstTime(Copper) {
+		t.Fatalf("copper first time after remove")
+	}
+
+	p.AddKey(Jade)
+	if p.FirstTime(Jade) {
+		t.Fatalf("jade first time after add")
+	}
+}
+
+func TestCommonKeys(t *testing.T) {
+	players := []*Player{
+		{Name: "Parzival", Keys: Copper | Jade | Crystal},
+		{Name: "Art3mis", Keys: Copper | Jade},
+		{Name: "Aech", Keys: Jade | Crystal},
+	}
+
+	if k := CommonKeys(players); k != Jade {
+		t.Fatalf("common: %q", k)
+	}
+
+	if k := CommonKeys(players[:1]); k != players[0].Keys {
+		t.Fatalf("single: %q", k)
+	}
+
+	if k := CommonKeys(nil); k != 0 {
+		t.Fatalf("empty: %q", k)
+	}
+}
+
+func TestKeyHandlers(t *testing.T) {
+	var called []KeySet
+	h := KeyHandlers{
+		Copper:  func() { called = append(called, Copper) },
+		Crystal: func() { called = append(called, Crystal) },
+	}
+
+	h.Dispatch(Copper | Jade | Crystal)
+	if len(called) != 2 || called[0] != Copper || called[1] != Crystal {
+		t.Fatalf("called: %v", called)
+	}
+}
+
+func TestCombinedKeys(t *testing.T) {
+	players := []*Player{
+		{Name: "Parzival", Keys: Copper},
+		{Name: "Art3mis", Keys: Jade},
+		{Name: "Aech"},
+	}
+
+	if k := CombinedKeys(players); k != Copper|Jade {
+		t.Fatalf("combined: %q", k)
+	}
+
+	if k := CombinedKeys(nil); k != 0 {
+		t.Fatalf("empty: %q", k)
+	}
+}
+
+func TestStringN(t *testing.T) {
+	k := Copper | Jade | Crystal
+	if s := k.StringN(3); s != k.String() {
+		t.Fatalf("3: %q", s)
+	}
+
+	if s := k.StringN(2); s != "copper|jade…(+1 more)" {
+		t.Fatalf("2: %q", s)
+	}
+
+	if s := k.StringN(0); s != "…(+3 more)" {
+		t.Fatalf("0: %q", s)
+	}
+}
+
+func TestTrimUnknown(t *testing.T) {
+	k := Copper | Crystal | 0x80
+	if k.IsValid() {
+		t.Fatalf("%d is valid", k)
+	}
+	if u := k.UnknownBits(); u != 0x80 {
+		t.Fatalf("unknown bits: %d", u)
+	}
+
+	clean, hadUnknown := k.TrimUnknown()
+	if clean != Copper|Crystal || !hadUnknown {
+		t.Fatalf("trim %d: %q, %v", k, clean, hadUnknown)
+	}
+
+	clean, hadUnknown = clean.TrimUnknown()
+	if clean != Copper|Crystal || hadUnknown {
+		t.Fatalf("trim valid: %q, %v", clean, hadUnknown)
+	}
+}
+
+func TestRandomKeySet(t *testing.T) {
+	r := rand.New(rand.NewSource(7))
+	var sawEmpty, sawAll bool
+	for i := 0; i < 1000; i++ {
+		k := RandomKeySet(r)
+		if !k.IsValid() {
+			t.Fatalf("invalid key set: %d", k)
+		}
+		sawEmpty = sawEmpty || k == 0
+		sawAll = sawAll || k == All()
+	}
+
+	if !sawEmpty || !sawAll {
+		t.Fatalf("empty: %v, all: %v", sawEmpty, sawAll)
+	}
+}
+
+func TestParseKeySet(t *testing.T) {
+	for _, k := range []KeySet{0, Copper, Jade | Crystal, All()} {
+		out, err := ParseKeySet(k.String())
+		if err != nil {
+			t.Fatalf("%q: %s", k, err)
+		}
+		if out != k {
+			t.Fatalf("%q: got %q", k, out)
+		}
+	}
+
+	if _, err := ParseKeySet("copper|gold"); err == nil {
+		t.Fatalf("no error on unknown name")
+	}
+}
+
+func TestMissing(t *testing.T) {
+	if m := Copper.Missing(Copper | Jade); m != Jade {
+		t.Fatalf("missing: %q", m)
+	}
+
+	if m := All().Missing(Copper | Jade); m != 0 {
+		t.Fatalf("all: %q", m)
+	}
+}
+
+func TestGrantAll(t *testing.T) {
+	p := Player{Name: "Parzival", Keys: Jade | 0x80}
+	p.GrantAll()
+	if p.Keys != All() {
+		t.Fatalf("grant all: %q", p.Keys)
+	}
+	for key := Copper; key < maxKey; key <<= 1 {
+		if !p.HasKey(key) {
+			t.Fatalf("%q not in %q", key, p.Keys)
+		}
+	}
+	if p.Keys.Count() != len(expectedNames) {
+		t.Fatalf("count: %d", p.Keys.Count())
+	}
+
+	p.RevokeAll()
+	if p.Keys != 0 {
+		t.Fatalf("revoke all: %q", p.Keys)
+	}
+}
+
+func TestScore(t *testing.T) {
+	weights := map[KeySet]int{
+		Copper:        1,
+		Crystal:       10,
+		Copper | Jade: 100, // not a single key, ignored
+	}
+
+	if s := (Copper | Jade | Crystal).Score(weights); s != 11 {
+		t.Fatalf("all: %d", s)
+	}
+
+	if s := Jade.Score(weights); s != 0 {
+		t.Fatalf("jade: %d", s)
+	}
+}
+
+func TestLess(t *testing.T) {
+	keys := []KeySet{Crystal, Copper | Jade, 0, Copper}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Less(keys[j]) })
+	expected := []KeySet{0, Copper, Copper | Jade, Crystal}
+	for i := range keys {
+		if keys[i] != expected[i] {
+			t.Fatalf("%v != %v", keys, expected)
+		}
+	}
+}
+
+func TestRankByKeys(t *testing.T) {
+	players := []Player{
+		{Name: "Aech", Keys: Copper},
+		{Name: "Parzival", Keys: Copper | Jade | Crystal},
+		{Name: "Art3mis", Keys: Copper | Jade},
+		{Name: "Shoto"},
+		{Name: "Daito", Keys: Jade | Crystal},
+	}
+	expected := map[string]int{
+		"Parzival": 1,
+		"Art3mis":  2,
+		"Daito":    2,
+		"Aech":     4,
+		"Shoto":    5,
+	}
+
+	ranks := RankByKeys(players)
+	if len(ranks) != len(expected) {
+		t.Fatalf("%v != %v", ranks, expected)
+	}
+	for name, rank := range expected {
+		if ranks[name] != rank {
+			t.Fatalf("%s: %d != %d", name, ranks[name], rank)
+		}
+	}
+}
+
+func TestIsSubsetOf(t *testing.T) {
+	if !(Copper | Jade).IsSubsetOf(All()) {
+		t.Fatalf("copper|jade not subset of all")
+	}
+	if (Copper | Jade).IsSubsetOf(Jade) {
+		t.Fatalf("copper|jade subset of jade")
+	}
+	if !KeySet(0).IsSubsetOf(0) {
+		t.Fatalf("empty not subset of empty")
+	}
+}
+
+func TestStrictHasKey(t *testing.T) {
+	p := Player{Name: "Parzival", Keys: Copper}
+	if !p.HasKey(Copper | Jade) {
+		t.Fatalf("any: copper|jade not in %q", p.Keys)
+	}
+
+	StrictHasKey = true
+	defer func() { StrictHasKey = false }()
+
+	if p.HasKey(Copper | Jade) {
+		t.Fatalf("strict: copper|jade in %q", p.Keys)
+	}
+	if !p.HasKey(Copper) {
+		t.Fatalf("strict: copper not in %q", p.Keys)
+	}
+}
+
+func TestDiffString(t *testing.T) {
+	cases := []struct {
+		a, b     KeySet
+		expected string
+	}{
+		{Copper, Crystal, "+crystal -copper"},
+		{0, Copper | Jade, "+copper +jade"},
+		{Copper | Jade, Jade, "-copper"},
+		{Jade, Jade, ""},
+		{Jade | 0x80, Jade, "-<unknown bit 7>"},
+	}
+
+	for _, tc := range cases {
+		if s := DiffString(tc.a, tc.b); s != tc.expected {
+			t.Fatalf("%q -> %q: %q != %q", tc.a, tc.b, s, tc.expected)
+		}
+	}
+}
+
+func TestOverlaps(t *testing.T) {
+	if !(Copper | Jade).Overlaps(Jade | Crystal) {
+		t.Fatalf("copper|jade and jade|crystal don't overlap")
+	}
+	if Copper.Overlaps(Jade | Crystal) {
+		t.Fatalf("copper and jade|crystal overlap")
+	}
+	if KeySet(0).Overlaps(All()) || All().Overlaps(0) {
+		t.Fatalf("empty overlaps")
+	}
+}
+
+func TestKeySetFromEnv(t *testing.T) {
+	const name = "GAME_STARTING_KEYS"
+
+	t.Setenv(name, " copper|jade\n")
+	k, err := KeySetFromEnv(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if k != Copper|Jade {
+		t.Fatalf("set: %q", k)
+	}
+
+	t.Setenv(name, "")
+	k, err = KeySetFromEnv(name)
+	if err != nil || k != 0 {
+		t.Fatalf("empty: %q, %v", k, err)
+	}
+
+	t.Setenv(name, "copper|gold")
+	if _, err := KeySetFromEnv(name); err == nil {
+		t.Fatalf("no error on unknown key")
+	}
+
+	k, err = KeySetFromEnv("GAME_NO_SUCH_VARIABLE")
+	if err != nil || k != 0 {
+		t.Fatalf("unset: %q, %v", k, err)
+	}
+}
+
+func TestSelect(t *testing.T) {
+	a, b := Copper|Crystal, Jade
+	if k := Select(true, a, b); k != a {
+		t.Fatalf("true: %q", k)
+	}
+	if k := Select(false, a, b); k != b {
+		t.Fatalf("false: %q", k)
+	}
+}
+
+func TestCheckKeys(t *testing.T) {
+	if err := checkKeys(keyNames); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := checkKeys(keyNames[:len(keyNames)-1]); err == nil {
+		t.Fatalf("no error on missing name")
+	}
+
+	names := append([]string{}, keyNames...)
+	if err := checkKeys(append(names, "gold")); err == nil {
+		t.Fatalf("no error on extra name")
+	}
+
+	for i, key := range []KeySet{Copper, Jade, Crystal} {
+		if definedKeys[i] != key {
+			t.Fatalf("key #%d: %d != %d", i, definedKeys[i], key)
+		}
+	}
+}
+
+func TestGoString(t *testing.T) {
+	cases := []struct {
+		k        KeySet
+		expected string
+	}{
+		{Copper, "bitmask.Copper"},
+		{Copper | Jade, "bitmask.Copper|bitmask.Jade"},
+		{0x80, "bitmask.KeySet(0x80)"},
+		{Crystal | 0x80, "bitmask.Crystal|bitmask.KeySet(0x80)"},
+		{0, "bitmask.KeySet(0x0)"},
+	}
+
+	for _, tc := range cases {
+		if s := fmt.Sprintf("%#v", tc.k); s != tc.expected {
+			t.Fatalf("%d: %q != %q", tc.k, s, tc.expected)
+		}
+	}
+}
+
+func TestPartition(t *testing.T) {
+	k := Copper | Jade | Crystal
+	inside, outside := k.Partition(Copper | Crystal)
+	if inside != Copper|Crystal || outside != Jade {
+		t.Fatalf("partition: %q, %q", inside, outside)
+	}
+	if inside|outside != k {
+		t.Fatalf("%q|%q != %q", inside, outside, k)
+	}
+}
+
+func TestKeyOfDay(t *testing.T) {
+	date := time.Date(2026, time.January, 1, 12, 0, 0, 0, time.UTC)
+	if k := KeyOfDay(date); k != Jade {
+		t.Fatalf("%s: %q", date, k)
+	}
+	if k := KeyOfDay(date); k != Jade {
+		t.Fatalf("%s again: %q", date, k)
+	}
+
+	date = time.Date(2026, time.January, 3, 12, 0, 0, 0, time.UTC)
+	if k := KeyOfDay(date); k != Copper {
+		t.Fatalf("%s: %q", date, k)
+	}
+
+	for i := 0; i < 366; i++ {
+		k := KeyOfDay(date.AddDate(0, 0, i))
+		if !k.IsSingle() || !k.IsValid() {
+			t.Fatalf("day %d: %d", i, k)
+		}
+	}
+}
+
+func TestAppendNames(t *testing.T) {
+	dst := []string{"keys:"}
+	dst = (Copper | Crystal | 0x80).AppendNames(dst)
+	expected := []string{"keys:", "copper", "crystal", "<unknown bit 7>"}
+	if len(dst) != len(expected) {
+		t.Fatalf("%v != %v", dst, expected)
+	}
+	for i := range dst {
+		if dst[i] != expected[i] {
+			t.Fatalf("%v != %v", dst, expected)
+		}
+	}
+
+	if names := KeySet(0).Names(); len(names) != 0 {
+		t.Fatalf("empty: %v", names)
+	}
+}
+
+func TestNextSuggested(t *testing.T) {
+	priority := []KeySet{Copper, Crystal, Jade}
+	p := Player{Name: "Parzival", Keys: Copper}
+	if k := p.NextSuggested(priority); k != Crystal {
+		t.Fatalf("copper: %q", k)
+	}
+
+	p.GrantAll()
+	if k := p.NextSuggested(priority); k != 0 {
+		t.Fatalf("all: %q", k)
+	}
+}
+
+func TestInRange(t *testing.T) {
+	low, high := Jade, Crystal
+	for _, k := range []KeySet{Jade, Copper | Jade, Crystal} {
+		if !k.InRange(low, high) {
+			t.Fatalf("%d not in [%d, %d]", k, low, high)
+		}
+	}
+	for _, k := range []KeySet{Copper, Copper | Crystal} {
+		if k.InRange(low, high) {
+			t.Fatalf("%d in [%d, %d]", k, low, high)
+		}
+	}
+}
+
+func TestChecksum(t *testing.T) {
+	k := Copper | Crystal
+	sum := k.Checksum()
+	if !VerifyKeySet(k, sum) {
+		t.Fatalf("%q: bad checksum %d", k, sum)
+	}
+
+	for i := 0; i < 8; i++ {
+		flipped := k ^ 1<<i
+		if VerifyKeySet(flipped, sum) {
+			t.Fatalf("bit %d: same checksum", i)
+		}
+	}
+}
+
+func TestEqualsAny(t *testing.T) {
+	k := Copper | Jade
+	if k.EqualsAny() {
+		t.Fatalf("no options")
+	}
+	if !k.EqualsAny(Copper, Jade|Copper) {
+		t.Fatalf("%q not in options", k)
+	}
+	if k.EqualsAny(Copper, Jade, All()) {
+		t.Fatalf("%q in options", k)
+	}
+}
+
+func TestFilterNames(t *testing.T) {
+	metal := func(k KeySet) bool { return k == Copper }
+	names := All().FilterNames(metal)
+	if len(names) != 1 || names[0] != "copper" {
+		t.Fatalf("metal: %v", names)
+	}
+
+	if names := Jade.FilterNames(metal); len(names) != 0 {
+		t.Fatalf("none: %v", names)
+	}
+}
+
+func TestNameTables(t *testing.T) {
+	if len(nameToKey) != len(keyNames) {
+		t.Fatalf("%d names, %d keys", len(keyNames), len(nameToKey))
+	}
+
+	for i, name := range keyNames {
+		key, ok := nameToKey[name]
+		if !ok {
+			t.Fatalf("%q not in nameToKey", name)
+		}
+		if key != 1<<i {
+			t.Fatalf("%q: key %d, expected %d", name, key, 1<<i)
+		}
+		if key.String() != name {
+			t.Fatalf("%q: String() = %q", name, key)
+		}
+	}
+}
+
+func TestSanitize(t *testing.T) {
+	k := Copper | Crystal
+	if s := k.Sanitize(); s != k {
+		t.Fatalf("no disabled keys: %q", s)
+	}
+
+	DisabledKeys = Crystal
+	defer func() { DisabledKeys = 0 }()
+
+	if s := k.Sanitize(); s != Copper {
+		t.Fatalf("crystal disabled: %q", s)
+	}
+	if s := k.MaskOut(Copper | Jade); s != Crystal {
+		t.Fatalf("mask out: %q", s)
+	}
+}
+
+func TestPadString(t *testing.T) {
+	cases := []struct {
+		k          KeySet
+		width      int
+		alignRight bool
+		expected   string
+	}{
+		{Jade, 6, false, "jade  "},
+		{Jade, 6, true, "  jade"},
+		{Jade, 4, false, "jade"},
+		{Copper | Jade, 8, false, "copper|…"},
+		{0, 3, false, "   "},
+	}
+
+	for _, tc := range cases {
+		s := tc.k.PadString(tc.width, tc.alignRight)
+		if s != tc.expected {
+			t.Fatalf("%q (%d, %v): %q != %q", tc.k, tc.width, tc.alignRight, s, tc.expected)
+		}
+	}
+}
+
+func TestCombine(t *testing.T) {
+	if k := Combine(); k != 0 {
+		t.Fatalf("empty: %q", k)
+	}
+	if k := Combine(Copper, Jade|Copper); k != Copper|Jade {
+		t.Fatalf("combine: %q", k)
+	}
+	if k := CombineAll([]KeySet{Copper, Crystal}); k != Copper|Crystal {
+		t.Fatalf("combine all: %q", k)
+	}
+	if k := CombineAll(nil); k != 0 {
+		t.Fatalf("combine all empty: %q", k)
+	}
+}
+
+func TestRegisterAlias(t *testing.T) {
+	defer func() { aliases = map[string]KeySet{} }()
+
+	if err := RegisterAlias("bronze", Copper); err != nil {
+		t.Fatal(err)
+	}
+
+	k, err := ParseKeySet("bronze|jade")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if k != Copper|Jade {
+		t.Fatalf("parse alias: %q", k)
+	}
+	if k.String() != "copper|jade" {
+		t.Fatalf("string: %q", k)
+	}
+
+	if err := RegisterAlias("gems", Jade|Crystal); err == nil {
+		t.Fatalf("no error on multiple keys")
+	}
+	if err := RegisterAlias("gold", 0x80); err == nil {
+		t.Fatalf("no error on unknown key")
+	}
+	if err := RegisterAlias("jade", Copper); err == nil {
+		t.Fatalf("no error on key name")
+	}
+}
+
+func TestToBools(t *testing.T) {
+	k := Copper | Crystal
+	m := k.ToBools()
+	expected := map[string]bool{"copper": true, "jade": false, "crystal": true}
+	if len(m) != len(expected) {
+		t.Fatalf("%v != %v", m, expected)
+	}
+	for name, v := range expected {
+		if ok, found := m[name]; !found || ok != v {
+			t.Fatalf("%s: %v != %v", name, m, expected)
+		}
+	}
+
+	out, err := KeySetFromBools(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != k {
+		t.Fatalf("round trip: %q != %q", out, k)
+	}
+
+	out, err = KeySetFromBools(map[string]bool{"jade": true})
+	if err != nil || out != Jade {
+		t.Fatalf("absent keys: %q, %v", out, err)
+	}
+
+	if _, err := KeySetFromBools(map[string]bool{"gold": false}); err == nil {
+		t.Fatalf("no error on unknown key")
+	}
+}
+
+func TestHasAllFast(t *testing.T) {
+	for v := 0; v < 256; v++ {
+		for m := 0; m < 256; m++ {
+			k, mask := KeySet(v), KeySet(m)
+			expected := true
+			for key := KeySet(1); key != 0; key <<= 1 {
+				if mask&key != 0 && k&key == 0 {
+					expected = false
+				}
+			}
+			if k.HasAllFast(mask) != expected {
+				t.Fatalf("%d has all of %d: %v", k, mask, !expected)
+			}
+		}
+	}
+}
+
+func TestMergeAuthoritative(t *testing.T) {
+	// All combinations for a single key
+	cases := []struct {
+		local, remote, base KeySet
+		expected            KeySet
+	}{
+		{0, 0, 0, 0},                // no change
+		{Copper, 0, 0, Copper},      // added local
+		{0, Copper, 0, Copper},      // added remote
+		{Copper, Copper, 0, Copper}, // added both
+		{0, 0, Copper, 0},           // removed both
+		{Copper, 0, Copper, 0},      // removed remote
+		{0, Copper, Copper, 0},      // removed local
+		{Copper, Copper, Copper, Copper},
+	}
+
+	for _, tc := range cases {
+		k := MergeAuthoritative(tc.local, tc.remote, tc.base)
+		if k != tc.expected {
+			t.Fatalf("local: %q, remote: %q, base: %q: %q != %q", tc.local, tc.remote, tc.base, k, tc.expected)
+		}
+	}
+
+	// local adds jade, remote removes copper and adds crystal
+	k := MergeAuthoritative(Copper|Jade, Crystal, Copper)
+	if k != Jade|Crystal {
+		t.Fatalf("mixed: %q", k)
+	}
+}
+
+func TestIsSingle(t *testing.T) {
+	for _, k := range []KeySet{Copper, Jade, Crystal, 0x80} {
+		if !k.IsSingle() {
+			t.Fatalf("%d is not single", k)
+		}
+	}
+	for _, k := range []KeySet{0, Copper | Jade, All()} {
+		if k.IsSingle() {
+			t.Fatalf("%d is single", k)
+		}
+	}
+}
+
+func TestStringCache(t *testing.T) {
+	for i := 0; i < 256; i++ {
+		k := KeySet(i)
+		if k.String() != k.format() {
+			t.Fatalf("%d: %q != %q", i, k.String(), k.format())
+		}
+	}
+}
+
+func TestBitIndex(t *testing.T) {
+	for i, key := range definedKeys {
+		n, ok := key.BitIndex()
+		if !ok || n != i {
+			t.Fatalf("%q: %d, %v", key, n, ok)
+		}
+	}
+
+	if n, _ := Crystal.BitIndex(); n != 2 {
+		t.Fatalf("crystal: %d", n)
+	}
+
+	for _, k := range []KeySet{0, Copper | Jade} {
+		if _, ok := k.BitIndex(); ok {
+			t.Fatalf("%d has index", k)
+		}
+	}
+}
+
+func TestIterFrom(t *testing.T) {
+	var keys []KeySet
+	collect := func(k KeySet) bool {
+		keys = append(keys, k)
+		return true
+	}
+
+	All().IterFrom(Jade, collect)
+	if len(keys) != 2 || keys[0] != Jade || keys[1] != Crystal {
+		t.Fatalf("from jade: %v", keys)
+	}
+
+	keys = nil
+	(Copper | Crystal).IterFrom(0, collect)
+	if len(keys) != 2 || keys[0] != Copper || keys[1] != Crystal {
+		t.Fatalf("from start: %v", keys)
+	}
+
+	keys = nil
+	All().IterFrom(0, func(k KeySet) bool {
+		keys = append(keys, k)
+		return k != Jade
+	})
+	if len(keys) != 2 || keys[1] != Jade {
+		t.Fatalf("early stop: %v", keys)
+	}
+}
+
+func TestDecodeOrDefault(t *testing.T) {
+	var errs []error
+	OnDecodeError = func(err error) { errs = append(errs, err) }
+	defer func() { OnDecodeError = nil }()
+
+	if k := DecodeOrDefault("copper|jade", Crystal); k != Copper|Jade {
+		t.Fatalf("valid: %q", k)
+	}
+	if len(errs) != 0 {
+		t.Fatalf("errors on valid: %v", errs)
+	}
+
+	if k := DecodeOrDefault("copper|gold", Crystal); k != Crystal {
+		t.Fatalf("invalid: %q", k)
+	}
+	if len(errs) != 1 {
+		t.Fatalf("errors on invalid: %v", errs)
+	}
+}
+
+func TestParseKeySetArgs(t *testing.T) {
+	k, err := ParseKeySetArgs([]string{"copper", "jade"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if k != Copper|Jade {
+		t.Fatalf("args: %q", k)
+	}
+
+	k, err = ParseKeySetArgs(nil)
+	if err != nil || k != 0 {
+		t.Fatalf("empty: %q, %v", k, err)
+	}
+
+	if _, err := ParseKeySetArgs([]string{"copper|jade"}); err == nil {
+		t.Fatalf("no error on joined names")
+	}
+}
+
+func TestAddKeyWithin(t *testing.T) {
+	p := Player{Name: "Parzival"}
+	if err := p.AddKeyWithin(Copper|Jade, Copper); err != nil {
+		t.Fatal(err)
+	}
+
+	err := p.AddKeyWithin(Copper|Jade, Jade|Crystal)
+	if err == nil {
+		t.Fatalf("no error on crystal")
+	}
+	if !strings.Contains(err.Error(), "crystal") {
+		t.Fatalf("crystal not in error: %s", err)
+	}
+	if p.Keys != Copper|Jade {
+		t.Fatalf("keys: %q", p.Keys)
+	}
+}
+
+func TestComparePlayers(t *testing.T) {
+	a := Player{Name: "Parzival", Keys: Copper | Crystal}
+	b := Player{Name: "Art3mis", Keys: Jade | Crystal}
+	expected := "Parzival has copper; Art3mis has jade; both have crystal"
+	if s := ComparePlayers(&a, &b); s != expected {
+		t.Fatalf("%q != %q", s, expected)
+	}
+
+	b.Keys = Copper
+	expected = "Parzival has crystal; both have copper"
+	if s := ComparePlayers(&a, &b); s != expected {
+		t.Fatalf("%q != %q", s, expected)
+	}
+
+	b.Keys = a.Keys
+	if s := ComparePlayers(&a, &b); s != "identical keyrings" {
+		t.Fatalf("identical: %q", s)
+	}
+}
+
+func TestNext(t *testing.T) {
+	k := Copper | Crystal
+	if n := k.Next(Copper); n != Crystal {
+		t.Fatalf("copper: %q", n)
+	}
+	if n := k.Next(Crystal); n != Copper {
+		t.Fatalf("crystal: %q", n)
+	}
+	if n := k.Next(Jade); n != Copper {
+		t.Fatalf("jade: %q", n)
+	}
+	if n := KeySet(0).Next(Copper); n != 0 {
+		t.Fatalf("empty: %q", n)
+	}
+}
+
+func TestNextCombination(t *testing.T) {
+	combs := []KeySet{0}
+	for k, ok := KeySet(0).NextCombination(); ok; k, ok = k.NextCombination() {
+		combs = append(combs, k)
+	}
+
+	if n := 1 << len(definedKeys); len(combs) != n {
+		t.Fatalf("%d combinations, expected %d", len(combs), n)
+	}
+	for i, k := range combs {
+		if k != KeySet(i) {
+			t.Fatalf("%d: %q", i, k)
+		}
+	}
+
+	if _, ok := All().NextCombination(); ok {
+		t.Fatalf("next after all")
+	}
+	if _, ok := KeySet(0x80).NextCombination(); ok {
+		t.Fatalf("next after unknown keys")
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	type change struct{ before, after KeySet }
+	var changes []change
+	OnKeysChange = func(p *Player, before, after KeySet) {
+		changes = append(changes, change{before, after})
+	}
+	defer func() { OnKeysChange = nil }()
+
+	p := Player{Name: "Parzival", Keys: Copper}
+	p.Update(
+		func(p *Player) { p.AddKey(Jade) },
+		func(p *Player) { p.AddKey(Crystal) },
+		func(p *Player) { p.RemoveKey(Copper) },
+	)
+
+	if len(changes) != 1 {
+		t.Fatalf("changes: %v", changes)
+	}
+	if c := changes[0]; c.before != Copper || c.after != Jade|Crystal {
+		t.Fatalf("change: %q -> %q", c.before, c.after)
+	}
+
+	p.Update(func(p *Player) { p.AddKey(Jade) })
+	if len(changes) != 1 {
+		t.Fatalf("change without new keys: %v", changes)
+	}
+}
+
+func TestID(t *testing.T) {
+	if id := (Copper | Crystal).ID(); id != "05" {
+		t.Fatalf("copper|crystal: %q", id)
+	}
+	if (Copper | Jade).ID() != (Jade | Copper).ID() {
+		t.Fatalf("equal sets, different IDs")
+	}
+	if Copper.ID() == Jade.ID() {
+		t.Fatalf("different sets, same ID")
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	cases := []struct {
+		k        KeySet
+		expected string
+	}{
+		{0, "holds no keys"},
+		{Jade, "holds the jade key"},
+		{Copper | Jade, "holds the copper and jade keys"},
+		{All(), "holds the copper, jade, and crystal keys"},
+	}
+
+	for _, tc := range cases {
+		if s := tc.k.Describe(); s != tc.expected {
+			t.Fatalf("%q: %q != %q", tc.k, s, tc.expected)
+		}
+	}
+}
+
+func TestSharedKeyCount(t *testing.T) {
+	a := Player{Name: "Parzival", Keys: Copper | Jade}
+	cases := []struct {
+		keys     KeySet
+		expected int
+	}{
+		{Crystal, 0},
+		{Jade | Crystal, 1},
+		{Copper | Jade, 2},
+	}
+
+	for _, tc := range cases {
+		b := Player{Name: "Art3mis", Keys: tc.keys}
+		if n := SharedKeyCount(&a, &b); n != tc.expected {
+			t.Fatalf("%q and %q: %d != %d", a.Keys, b.Keys, n, tc.expected)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package bitmask
+
+import "fmt"
+
+// Unsigned is a constraint for unsigned integer types, including types based
+// on them such as KeySet
+type Unsigned interface {
+	~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr
+}
+
+// Convert converts the bitmask src to type U
+// It returns an error if src has bits that don't fit in U
+func Convert[T, U Unsigned](src T) (U, error) {
+	dst := U(src)
+	if T(dst) != src {
+		return 0, fmt.Errorf("%#x does not fit in %T", uint64(src), dst)
+	}
+	return dst, nil
+}
+
+// PackBools packs bools to a bitmask, bools[i] is bit i
+// Only the first 64 bools are packed
+func PackBools(bools []bool) uint64 {
+	var v uint64
+	for i, b := range bools {
+		if i == 64 {
+			break
+		}
+		if b {
+			v |= 1 << i
+		}
+	}
+	return v
+}
+
+// UnpackBools returns the first n bits of v as bools, see PackBools
+// n is clamped to the range 0 to 64
+func UnpackBools(v uint64, n int) []bool {
+	n = max(0, min(n, 64))
+	bools := make([]bool, n)
+	for i := 0; i < n; i++ {
+		bools[i] = v&(1<<i) != 0
+	}
+	return bools
+}
+
+// ToUint returns k as a uint
+func (k KeySet) ToUint() uint {
+	return uint(k)
+}
+
+// FromUint returns u as a KeySet, it returns an error if u doesn't fit in a KeySet
+func FromUint(u uint) (KeySet, error) {
+	return Convert[uint, KeySet](u)
+}
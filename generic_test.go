@@ -0,0 +1,69 @@
+package bitmask
+
+import "testing"
+
+func TestConvert(t *testing.T) {
+	k, err := Convert[uint16, KeySet](0x05)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if k != Copper|Crystal {
+		t.Fatalf("narrow: %q", k)
+	}
+
+	if _, err := Convert[uint16, KeySet](0x105); err == nil {
+		t.Fatalf("no error on lost bits")
+	}
+
+	w, err := Convert[KeySet, uint32](Jade | Crystal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w != 6 {
+		t.Fatalf("widen: %d", w)
+	}
+}
+
+func TestPackBools(t *testing.T) {
+	bools := []bool{true, false, true, true, false}
+	v := PackBools(bools)
+	if v != 0b01101 {
+		t.Fatalf("pack: %b", v)
+	}
+
+	out := UnpackBools(v, len(bools))
+	if len(out) != len(bools) {
+		t.Fatalf("unpack: %v", out)
+	}
+	for i := range bools {
+		if out[i] != bools[i] {
+			t.Fatalf("unpack: %v != %v", out, bools)
+		}
+	}
+
+	if v := PackBools(nil); v != 0 {
+		t.Fatalf("empty: %b", v)
+	}
+
+	if out := UnpackBools(v, -1); len(out) != 0 {
+		t.Fatalf("negative: %v", out)
+	}
+	if out := UnpackBools(v, 100); len(out) != 64 {
+		t.Fatalf("over 64: %d", len(out))
+	}
+}
+
+func TestFromUint(t *testing.T) {
+	k := Copper | Crystal
+	out, err := FromUint(k.ToUint())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != k {
+		t.Fatalf("round trip: %q != %q", out, k)
+	}
+
+	if _, err := FromUint(0x100); err == nil {
+		t.Fatalf("no error on overflow")
+	}
+}
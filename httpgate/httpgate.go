@@ -0,0 +1,38 @@
+// Package httpgate uses a KeySet as a set of features required by HTTP handlers
+package httpgate
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/353words/bitmask"
+)
+
+type ctxKey struct{}
+
+// NewContext returns a context holding keys
+func NewContext(ctx context.Context, keys bitmask.KeySet) context.Context {
+	return context.WithValue(ctx, ctxKey{}, keys)
+}
+
+// FromContext returns the keys in ctx
+func FromContext(ctx context.Context) (bitmask.KeySet, bool) {
+	keys, ok := ctx.Value(ctxKey{}).(bitmask.KeySet)
+	return keys, ok
+}
+
+// RequireKeys calls h only if the request context has all required keys
+// Otherwise it returns a 403 with the missing keys
+func RequireKeys(required bitmask.KeySet, h http.Handler) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		keys, _ := FromContext(r.Context())
+		if missing := keys.Missing(required); missing != 0 {
+			msg := fmt.Sprintf("missing keys: %s", missing)
+			http.Error(w, msg, http.StatusForbidden)
+			return
+		}
+		h.ServeHTTP(w, r)
+	}
+	return http.HandlerFunc(fn)
+}
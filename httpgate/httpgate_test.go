@@ -0,0 +1,43 @@
+package httpgate
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/353words/bitmask"
+)
+
+func TestRequireKeys(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("welcome"))
+	})
+	h := RequireKeys(bitmask.Copper|bitmask.Jade, ok)
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r = r.WithContext(NewContext(r.Context(), bitmask.Copper|bitmask.Jade|bitmask.Crystal))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("all keys: %d", w.Code)
+	}
+
+	r = httptest.NewRequest(http.MethodGet, "/", nil)
+	r = r.WithContext(NewContext(r.Context(), bitmask.Copper))
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("missing key: %d", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), "jade") {
+		t.Fatalf("missing key body: %q", w.Body.String())
+	}
+
+	r = httptest.NewRequest(http.MethodGet, "/", nil)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("no keys: %d", w.Code)
+	}
+}
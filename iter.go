@@ -0,0 +1,26 @@
+package bitmask
+
+import (
+	"fmt"
+	"iter"
+)
+
+// Named returns an iterator over the keys in k and their names
+// Unknown bits are named "<unknown: N>" where N is the bit value
+func (k KeySet) Named() iter.Seq2[KeySet, string] {
+	return func(yield func(KeySet, string) bool) {
+		for key := KeySet(1); key != 0; key <<= 1 {
+			if k&key == 0 {
+				continue
+			}
+
+			name := fmt.Sprintf("<unknown: %d>", key)
+			if key < maxKey {
+				name = key.String()
+			}
+			if !yield(key, name) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package bitmask
+
+import "testing"
+
+func TestNamed(t *testing.T) {
+	var keys []KeySet
+	var names []string
+	for key, name := range (Copper | Crystal | 0x80).Named() {
+		keys = append(keys, key)
+		names = append(names, name)
+	}
+
+	expected := []string{"copper", "crystal", "<unknown: 128>"}
+	if len(names) != len(expected) {
+		t.Fatalf("%v != %v", names, expected)
+	}
+	for i := range names {
+		if names[i] != expected[i] {
+			t.Fatalf("%v != %v", names, expected)
+		}
+	}
+	if keys[0] != Copper || keys[1] != Crystal || keys[2] != 0x80 {
+		t.Fatalf("keys: %v", keys)
+	}
+
+	n := 0
+	for range All().Named() {
+		n++
+		break
+	}
+	if n != 1 {
+		t.Fatalf("break: %d", n)
+	}
+}
@@ -0,0 +1,137 @@
+package bitmask
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// JSONFormat is the JSON encoding of a KeySet
+type JSONFormat int
+
+const (
+	NameFormat    JSONFormat = iota // ["copper", "jade"]
+	NumericFormat                   // 3
+)
+
+var jsonFormat = NameFormat
+
+// SetJSONFormat sets the format used by KeySet.MarshalJSON
+// It should be called once at program startup, the default is NameFormat
+func SetJSONFormat(f JSONFormat) {
+	jsonFormat = f
+}
+
+// MarshalJSON implements the json.Marshaler interface
+func (k KeySet) MarshalJSON() ([]byte, error) {
+	if !k.IsValid() {
+		return nil, fmt.Errorf("unknown keys: %d", k.UnknownBits())
+	}
+
+	if jsonFormat == NumericFormat {
+		return json.Marshal(uint8(k))
+	}
+
+	return json.Marshal(k.names())
+}
+
+// LenientKeyDecoding controls how unknown key names are decoded from JSON
+// When false (the default) unknown names are an error, when true they are skipped
+var LenientKeyDecoding = false
+
+// UnmarshalJSON implements the json.Unmarshaler interface
+// It accepts both the name and the numeric format, null leaves k unchanged
+func (k *KeySet) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+
+	keys, _, err := DecodeKeySetJSON(data)
+	if err != nil {
+		return err
+	}
+	*k = keys
+	return nil
+}
+
+// DecodeKeySetJSON decodes a KeySet from JSON and returns the names skipped
+// due to LenientKeyDecoding
+func DecodeKeySetJSON(data []byte) (KeySet, []string, error) {
+	var n uint8
+	if err := json.Unmarshal(data, &n); err == nil {
+		if KeySet(n) >= maxKey {
+			return 0, nil, fmt.Errorf("unknown keys: %d", n)
+		}
+		return KeySet(n), nil, nil
+	}
+
+	var names []string
+	if err := json.Unmarshal(data, &names); err != nil {
+		return 0, nil, err
+	}
+
+	var keys KeySet
+	var skipped []string
+	for _, name := range names {
+		key, ok := keyByName(name)
+		if !ok {
+			if LenientKeyDecoding {
+				skipped = append(skipped, name)
+				continue
+			}
+			return 0, nil, fmt.Errorf("unknown key: %q", name)
+		}
+		keys |= key
+	}
+	return keys, skipped, nil
+}
+
+// StreamRosterJSON writes players as a JSON array to w, one player at a time
+// On error it reads the rest of players before returning, so producers are not
+// blocked.
+func StreamRosterJSON(w io.Writer, players <-chan Player) error {
+	if err := writeRoster(w, players); err != nil {
+		for range players {
+		}
+		return err
+	}
+	return nil
+}
+
+// rosterPlayer is a Player as written by StreamRosterJSON
+type rosterPlayer struct {
+	Name string
+	Keys []string
+}
+
+func writeRoster(w io.Writer, players <-chan Player) error {
+	if _, err := io.WriteString(w, "["); err != nil {
+		return err
+	}
+
+	first := true
+	for p := range players {
+		if !first {
+			if _, err := io.WriteString(w, ","); err != nil {
+				return err
+			}
+		}
+		first = false
+
+		if !p.Keys.IsValid() {
+			return fmt.Errorf("%s: unknown keys: %d", p.Name, p.Keys.UnknownBits())
+		}
+		// Keys are always names, regardless of SetJSONFormat
+		rp := rosterPlayer{Name: p.Name, Keys: p.Keys.names()}
+		data, err := json.Marshal(rp)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+	}
+
+	_, err := io.WriteString(w, "]")
+	return err
+}
@@ -0,0 +1,210 @@
+package bitmask
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestJSON(t *testing.T) {
+	data, err := json.Marshal(Copper | Crystal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `["copper","crystal"]` {
+		t.Fatalf("names: %s", data)
+	}
+
+	var k KeySet
+	if err := json.Unmarshal(data, &k); err != nil {
+		t.Fatal(err)
+	}
+	if k != Copper|Crystal {
+		t.Fatalf("unmarshal names: %q", k)
+	}
+
+	data, err = json.Marshal(KeySet(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `[]` {
+		t.Fatalf("empty: %s", data)
+	}
+}
+
+func TestJSONNumeric(t *testing.T) {
+	SetJSONFormat(NumericFormat)
+	defer SetJSONFormat(NameFormat)
+
+	data, err := json.Marshal(Copper | Crystal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `5` {
+		t.Fatalf("numeric: %s", data)
+	}
+
+	var k KeySet
+	if err := json.Unmarshal(data, &k); err != nil {
+		t.Fatal(err)
+	}
+	if k != Copper|Crystal {
+		t.Fatalf("unmarshal numeric: %q", k)
+	}
+}
+
+func TestJSONNull(t *testing.T) {
+	k := Copper
+	if err := json.Unmarshal([]byte("null"), &k); err != nil {
+		t.Fatal(err)
+	}
+	if k != Copper {
+		t.Fatalf("null: %q", k)
+	}
+}
+
+func TestJSONUnknown(t *testing.T) {
+	var k KeySet
+	if err := json.Unmarshal([]byte(`["copper","gold"]`), &k); err == nil {
+		t.Fatalf("no error on unknown name")
+	}
+
+	if err := json.Unmarshal([]byte(`8`), &k); err == nil {
+		t.Fatalf("no error on unknown bits")
+	}
+
+	if _, err := json.Marshal(KeySet(0x80)); err == nil {
+		t.Fatalf("names: no error on marshal unknown bits")
+	}
+
+	SetJSONFormat(NumericFormat)
+	defer SetJSONFormat(NameFormat)
+	if _, err := json.Marshal(KeySet(0x80)); err == nil {
+		t.Fatalf("numeric: no error on marshal unknown bits")
+	}
+}
+
+func TestPlayerJSON(t *testing.T) {
+	p := Player{Name: "Parzival"}
+	p.AddKey(Copper)
+
+	data, err := json.Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"Name":"Parzival","Keys":["copper"]}` {
+		t.Fatalf("marshal: %s", data)
+	}
+}
+
+func TestJSONLenient(t *testing.T) {
+	data := []byte(`["copper","gold","jade"]`)
+
+	if _, _, err := DecodeKeySetJSON(data); err == nil {
+		t.Fatalf("strict: no error on unknown name")
+	}
+
+	LenientKeyDecoding = true
+	defer func() { LenientKeyDecoding = false }()
+
+	k, skipped, err := DecodeKeySetJSON(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if k != Copper|Jade {
+		t.Fatalf("lenient: %q", k)
+	}
+	if len(skipped) != 1 || skipped[0] != "gold" {
+		t.Fatalf("skipped: %v", skipped)
+	}
+
+	if err := json.Unmarshal(data, &k); err != nil {
+		t.Fatal(err)
+	}
+	if k != Copper|Jade {
+		t.Fatalf("unmarshal lenient: %q", k)
+	}
+}
+
+func TestStreamRosterJSON(t *testing.T) {
+	roster := []Player{
+		{Name: "Parzival", Keys: Copper | Jade},
+		{Name: "Art3mis", Keys: Crystal},
+		{Name: "Aech"},
+	}
+
+	for n := 0; n <= len(roster); n++ {
+		ch := make(chan Player)
+		go func() {
+			for _, p := range roster[:n] {
+				ch <- p
+			}
+			close(ch)
+		}()
+
+		var buf bytes.Buffer
+		if err := StreamRosterJSON(&buf, ch); err != nil {
+			t.Fatal(err)
+		}
+
+		var players []Player
+		if err := json.Unmarshal(buf.Bytes(), &players); err != nil {
+			t.Fatalf("%d: %s (%s)", n, err, buf.String())
+		}
+		if len(players) != n {
+			t.Fatalf("%d: got %d players", n, len(players))
+		}
+		for i, p := range players {
+			if !p.Equal(roster[i]) {
+				t.Fatalf("%d: %v != %v", n, p, roster[i])
+			}
+		}
+	}
+
+	if !bytes.Contains(mustMarshal(t, roster[0]), []byte(`["copper","jade"]`)) {
+		t.Fatalf("keys are not names")
+	}
+}
+
+func TestStreamRosterJSONNumeric(t *testing.T) {
+	SetJSONFormat(NumericFormat)
+	defer SetJSONFormat(NameFormat)
+
+	ch := make(chan Player, 1)
+	ch <- Player{Name: "Parzival", Keys: Copper | Jade}
+	close(ch)
+
+	var buf bytes.Buffer
+	if err := StreamRosterJSON(&buf, ch); err != nil {
+		t.Fatal(err)
+	}
+	expected := `[{"Name":"Parzival","Keys":["copper","jade"]}]`
+	if buf.String() != expected {
+		t.Fatalf("%s != %s", buf.String(), expected)
+	}
+}
+
+func TestStreamRosterJSONWriteError(t *testing.T) {
+	ch := make(chan Player)
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 3; i++ {
+			ch <- Player{Name: "Parzival", Keys: Copper}
+		}
+		close(ch)
+		close(done)
+	}()
+
+	if err := StreamRosterJSON(errWriter{}, ch); err == nil {
+		t.Fatalf("no error on write error")
+	}
+	<-done // producer is not blocked
+}
+
+func mustMarshal(t *testing.T, v interface{}) []byte {
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
@@ -0,0 +1,68 @@
+package bitmask
+
+import "fmt"
+
+// KeyInfo is display information on a key
+type KeyInfo struct {
+	Name   string
+	Color  string
+	Rarity int
+}
+
+var keyInfos = map[KeySet]KeyInfo{}
+
+// RegisterKeyInfo registers display information for a single key
+// info.Name may be empty, otherwise it must be the key name. It should be
+// called at program startup.
+func RegisterKeyInfo(k KeySet, info KeyInfo) error {
+	if !k.IsSingle() || !k.IsValid() {
+		return fmt.Errorf("%d is not a single key", k)
+	}
+
+	if info.Name == "" {
+		info.Name = k.String()
+	}
+	if info.Name != k.String() {
+		return fmt.Errorf("%q: bad name %q", k, info.Name)
+	}
+
+	keyInfos[k] = info
+	return nil
+}
+
+// KeyInfoFor returns the display information for a single key
+// Keys without registered information have only a Name
+func KeyInfoFor(k KeySet) (KeyInfo, bool) {
+	if !k.IsSingle() || !k.IsValid() {
+		return KeyInfo{}, false
+	}
+
+	if info, ok := keyInfos[k]; ok {
+		return info, true
+	}
+	return KeyInfo{Name: k.String()}, true
+}
+
+var categories = map[KeySet]string{}
+
+// AssignCategory assigns the keys in k to category
+// It should be called at program startup.
+func AssignCategory(k KeySet, category string) {
+	for _, key := range definedKeys {
+		if k&key != 0 {
+			categories[key] = category
+		}
+	}
+}
+
+// ByCategory returns the keys in k grouped by their category
+// Keys without a category are under the empty string
+func (k KeySet) ByCategory() map[string]KeySet {
+	groups := make(map[string]KeySet)
+	for _, key := range definedKeys {
+		if k&key != 0 {
+			groups[categories[key]] |= key
+		}
+	}
+	return groups
+}
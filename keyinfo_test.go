@@ -0,0 +1,59 @@
+package bitmask
+
+import "testing"
+
+func TestKeyInfo(t *testing.T) {
+	defer func() { keyInfos = map[KeySet]KeyInfo{} }()
+
+	if err := RegisterKeyInfo(Crystal, KeyInfo{Color: "#88ccff", Rarity: 3}); err != nil {
+		t.Fatal(err)
+	}
+
+	info, ok := KeyInfoFor(Crystal)
+	expected := KeyInfo{Name: "crystal", Color: "#88ccff", Rarity: 3}
+	if !ok || info != expected {
+		t.Fatalf("crystal: %+v, %v", info, ok)
+	}
+	if Crystal.String() != "crystal" {
+		t.Fatalf("string: %q", Crystal)
+	}
+
+	info, ok = KeyInfoFor(Jade)
+	if !ok || info != (KeyInfo{Name: "jade"}) {
+		t.Fatalf("jade: %+v, %v", info, ok)
+	}
+
+	if _, ok := KeyInfoFor(Copper | Jade); ok {
+		t.Fatalf("info for multiple keys")
+	}
+
+	if err := RegisterKeyInfo(Copper|Jade, KeyInfo{}); err == nil {
+		t.Fatalf("no error on multiple keys")
+	}
+	if err := RegisterKeyInfo(Copper, KeyInfo{Name: "bronze"}); err == nil {
+		t.Fatalf("no error on bad name")
+	}
+}
+
+func TestByCategory(t *testing.T) {
+	defer func() { categories = map[KeySet]string{} }()
+
+	AssignCategory(Copper, "metals")
+	AssignCategory(Jade|Crystal, "gems")
+
+	groups := All().ByCategory()
+	if len(groups) != 2 || groups["metals"] != Copper || groups["gems"] != Jade|Crystal {
+		t.Fatalf("all: %v", groups)
+	}
+
+	groups = (Copper | Jade).ByCategory()
+	if len(groups) != 2 || groups["metals"] != Copper || groups["gems"] != Jade {
+		t.Fatalf("copper|jade: %v", groups)
+	}
+
+	categories = map[KeySet]string{}
+	groups = (Copper | Jade).ByCategory()
+	if len(groups) != 1 || groups[""] != Copper|Jade {
+		t.Fatalf("no categories: %v", groups)
+	}
+}
@@ -0,0 +1,35 @@
+package bitmask
+
+import "fmt"
+
+// KeyPatch is a change to a KeySet
+type KeyPatch struct {
+	Add    KeySet
+	Remove KeySet
+}
+
+// DiffPatch returns the patch that changes from to to
+func DiffPatch(from, to KeySet) KeyPatch {
+	added, removed := from.Diff(to)
+	return KeyPatch{Add: added, Remove: removed}
+}
+
+// Apply returns k with the patch applied
+func (p KeyPatch) Apply(k KeySet) KeySet {
+	return (k | p.Add) &^ p.Remove
+}
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface
+// A patch is encoded in two bytes: add and remove
+func (p KeyPatch) MarshalBinary() ([]byte, error) {
+	return []byte{byte(p.Add), byte(p.Remove)}, nil
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface
+func (p *KeyPatch) UnmarshalBinary(data []byte) error {
+	if len(data) != 2 {
+		return fmt.Errorf("bad patch size: %d (should be 2)", len(data))
+	}
+	p.Add, p.Remove = KeySet(data[0]), KeySet(data[1])
+	return nil
+}
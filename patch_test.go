@@ -0,0 +1,34 @@
+package bitmask
+
+import "testing"
+
+func TestKeyPatch(t *testing.T) {
+	from, to := Copper|Jade, Jade|Crystal
+	p := DiffPatch(from, to)
+	if p.Add != Crystal || p.Remove != Copper {
+		t.Fatalf("diff: %+v", p)
+	}
+	if k := p.Apply(from); k != to {
+		t.Fatalf("apply: %q", k)
+	}
+
+	data, err := p.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != 2 {
+		t.Fatalf("size: %d", len(data))
+	}
+
+	var p2 KeyPatch
+	if err := p2.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if p2 != p {
+		t.Fatalf("round trip: %+v != %+v", p2, p)
+	}
+
+	if err := p2.UnmarshalBinary([]byte{1}); err == nil {
+		t.Fatalf("no error on short data")
+	}
+}
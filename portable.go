@@ -0,0 +1,41 @@
+package bitmask
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Portable format, version 1:
+// Key names, sorted alphabetically and joined with "," without spaces
+// (e.g. "copper,crystal"). The empty set is the empty string.
+// Only key names are valid, aliases are not.
+
+// PortableEncode returns k in the portable format for non Go clients
+// It returns an error if k has unknown keys.
+func (k KeySet) PortableEncode() (string, error) {
+	if !k.IsValid() {
+		return "", fmt.Errorf("unknown keys: %d", k.UnknownBits())
+	}
+
+	names := k.names()
+	sort.Strings(names)
+	return strings.Join(names, ","), nil
+}
+
+// PortableDecode parses a KeySet in the portable format, see PortableEncode
+func PortableDecode(s string) (KeySet, error) {
+	if s == "" {
+		return 0, nil
+	}
+
+	var keys KeySet
+	for _, name := range strings.Split(s, ",") {
+		key, ok := nameToKey[name]
+		if !ok {
+			return 0, fmt.Errorf("unknown key: %q", name)
+		}
+		keys |= key
+	}
+	return keys, nil
+}
@@ -0,0 +1,38 @@
+package bitmask
+
+import "testing"
+
+func TestPortable(t *testing.T) {
+	s, err := (Copper | Jade | Crystal).PortableEncode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "copper,crystal,jade" {
+		t.Fatalf("encode: %q", s)
+	}
+
+	for i := 0; i <= int(All()); i++ {
+		k := KeySet(i)
+		s, err := k.PortableEncode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, err := PortableDecode(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if out != k {
+			t.Fatalf("%q: %q != %q", s, out, k)
+		}
+	}
+
+	if _, err := KeySet(0x80).PortableEncode(); err == nil {
+		t.Fatalf("no error on unknown keys")
+	}
+
+	for _, s := range []string{"copper,gold", "copper|jade", "copper, jade"} {
+		if _, err := PortableDecode(s); err == nil {
+			t.Fatalf("%q: no error", s)
+		}
+	}
+}
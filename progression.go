@@ -0,0 +1,31 @@
+package bitmask
+
+import "sort"
+
+// Progression maps the keys required for a stage to the stage name
+type Progression map[KeySet]string
+
+// Stage returns the most restrictive stage the player has all the keys for,
+// or an empty string if there's none.
+// Stages requiring more keys are more restrictive, ties are broken by higher
+// key value.
+func (pr Progression) Stage(p *Player) string {
+	required := make([]KeySet, 0, len(pr))
+	for k := range pr {
+		required = append(required, k)
+	}
+	sort.Slice(required, func(i, j int) bool {
+		a, b := required[i], required[j]
+		if a.Count() != b.Count() {
+			return a.Count() > b.Count()
+		}
+		return a > b
+	})
+
+	for _, k := range required {
+		if k.IsSubsetOf(p.Keys) {
+			return pr[k]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,26 @@
+package bitmask
+
+import "testing"
+
+func TestProgression(t *testing.T) {
+	pr := Progression{
+		Copper:                  "Copper Gate",
+		Copper | Jade:           "Jade Hall",
+		Copper | Jade | Crystal: "Crystal Sanctum",
+	}
+
+	p := Player{Name: "Parzival", Keys: Copper | Jade}
+	if s := pr.Stage(&p); s != "Jade Hall" {
+		t.Fatalf("copper|jade: %q", s)
+	}
+
+	p.Keys = Jade | Crystal
+	if s := pr.Stage(&p); s != "" {
+		t.Fatalf("jade|crystal: %q", s)
+	}
+
+	p.GrantAll()
+	if s := pr.Stage(&p); s != "Crystal Sanctum" {
+		t.Fatalf("all: %q", s)
+	}
+}
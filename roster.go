@@ -0,0 +1,88 @@
+package bitmask
+
+import (
+	"sort"
+	"sync"
+)
+
+// Roster is a set of players by name, it is safe for concurrent use
+type Roster struct {
+	mu      sync.RWMutex
+	players map[string]*Player
+}
+
+// Add adds a player to the roster, replacing a player with the same name
+func (r *Roster) Add(p *Player) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.players == nil {
+		r.players = make(map[string]*Player)
+	}
+	r.players[p.Name] = p
+}
+
+// Get returns the player with name
+func (r *Roster) Get(name string) (*Player, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	p, ok := r.players[name]
+	return p, ok
+}
+
+// PlayersWithKey returns a copy of the players that have all the keys in key,
+// sorted by name
+func (r *Roster) PlayersWithKey(key KeySet) []Player {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var players []Player
+	for _, p := range r.players {
+		if p.Keys&key == key {
+			players = append(players, *p)
+		}
+	}
+
+	sort.Slice(players, func(i, j int) bool { return players[i].Name < players[j].Name })
+	return players
+}
+
+// RosterIndex counts the players holding each key
+// Players must not change their keys between Add and Remove.
+type RosterIndex struct {
+	counts [8]int // index is bit position
+}
+
+// Add adds the player keys to the index
+func (ri *RosterIndex) Add(p *Player) {
+	ri.update(p.Keys, 1)
+}
+
+// Remove removes the player keys from the index
+func (ri *RosterIndex) Remove(p *Player) {
+	ri.update(p.Keys, -1)
+}
+
+func (ri *RosterIndex) update(k KeySet, delta int) {
+	for i := range ri.counts {
+		if k&(1<<i) != 0 {
+			ri.counts[i] += delta
+		}
+	}
+}
+
+// CountWith returns the number of players that have the single key key
+// It returns 0 if key is not a single key
+func (ri *RosterIndex) CountWith(key KeySet) int {
+	i, ok := key.BitIndex()
+	if !ok {
+		return 0
+	}
+	return ri.counts[i]
+}
+
+// AnyoneHas returns true if any player has the single key key
+func (ri *RosterIndex) AnyoneHas(key KeySet) bool {
+	return ri.CountWith(key) > 0
+}
@@ -0,0 +1,89 @@
+package bitmask
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestRoster(t *testing.T) {
+	var r Roster
+	r.Add(&Player{Name: "Parzival", Keys: Copper | Jade})
+	r.Add(&Player{Name: "Art3mis", Keys: Copper | Jade | Crystal})
+	r.Add(&Player{Name: "Aech", Keys: Copper})
+
+	p, ok := r.Get("Aech")
+	if !ok || p.Keys != Copper {
+		t.Fatalf("get: %v, %v", p, ok)
+	}
+
+	if _, ok := r.Get("Sorrento"); ok {
+		t.Fatalf("get unknown player")
+	}
+
+	players := r.PlayersWithKey(Copper | Jade)
+	if len(players) != 2 || players[0].Name != "Art3mis" || players[1].Name != "Parzival" {
+		t.Fatalf("with key: %v", players)
+	}
+}
+
+func TestRosterConcurrent(t *testing.T) {
+	var r Roster
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				r.Add(&Player{Name: fmt.Sprintf("p%d-%d", i, j), Keys: KeySet(j) & All()})
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				for _, p := range r.PlayersWithKey(Crystal) {
+					if !p.HasKey(Crystal) {
+						t.Errorf("%s: crystal not in %q", p.Name, p.Keys)
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := len(r.PlayersWithKey(0)); n != 800 {
+		t.Fatalf("players: %d", n)
+	}
+}
+
+func TestRosterIndex(t *testing.T) {
+	players := []*Player{
+		{Name: "Parzival", Keys: Copper | Crystal},
+		{Name: "Art3mis", Keys: Copper | Jade},
+		{Name: "Aech", Keys: Copper},
+	}
+
+	var ri RosterIndex
+	for _, p := range players {
+		ri.Add(p)
+	}
+
+	if n := ri.CountWith(Copper); n != 3 {
+		t.Fatalf("copper: %d", n)
+	}
+	if !ri.AnyoneHas(Crystal) {
+		t.Fatalf("no one has crystal")
+	}
+
+	ri.Remove(players[0])
+	if n := ri.CountWith(Copper); n != 2 {
+		t.Fatalf("copper after remove: %d", n)
+	}
+	if ri.AnyoneHas(Crystal) {
+		t.Fatalf("someone has crystal after remove")
+	}
+
+	if n := ri.CountWith(Copper | Jade); n != 0 {
+		t.Fatalf("multiple keys: %d", n)
+	}
+}
@@ -0,0 +1,12 @@
+package bitmask
+
+import "log/slog"
+
+// LogValue implements the slog.LogValuer interface
+// Keys are logged as names, an empty set is logged as "none"
+func (k KeySet) LogValue() slog.Value {
+	if k == 0 {
+		return slog.StringValue("none")
+	}
+	return slog.StringValue(k.String())
+}
@@ -0,0 +1,24 @@
+package bitmask
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestLogValue(t *testing.T) {
+	var buf bytes.Buffer
+	log := slog.New(slog.NewTextHandler(&buf, nil))
+
+	log.Info("player", "keys", Copper|Jade)
+	if !strings.Contains(buf.String(), "keys=copper|jade") {
+		t.Fatalf("keys: %q", buf.String())
+	}
+
+	buf.Reset()
+	log.Info("player", "keys", KeySet(0))
+	if !strings.Contains(buf.String(), "keys=none") {
+		t.Fatalf("empty: %q", buf.String())
+	}
+}
@@ -0,0 +1,42 @@
+package bitmask
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// DecodeKeySets parses a KeySet from every line in r, see ParseKeySet
+func DecodeKeySets(r io.Reader) ([]KeySet, error) {
+	var sets []KeySet
+	s := bufio.NewScanner(r)
+	lnum := 0
+	for s.Scan() {
+		lnum++
+		k, err := ParseKeySet(strings.TrimSpace(s.Text()))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lnum, err)
+		}
+		sets = append(sets, k)
+	}
+
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return sets, nil
+}
+
+// EncodeKeySets writes every set in sets to w in its own line, see DecodeKeySets
+func EncodeKeySets(w io.Writer, sets []KeySet) error {
+	bw := bufio.NewWriter(w)
+	for _, k := range sets {
+		if !k.IsValid() {
+			return fmt.Errorf("unknown keys: %d", k.UnknownBits())
+		}
+		if _, err := fmt.Fprintln(bw, k); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,71 @@
+package bitmask
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestDecodeKeySets(t *testing.T) {
+	r := strings.NewReader("copper|jade\n\ncrystal\n")
+	sets, err := DecodeKeySets(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []KeySet{Copper | Jade, 0, Crystal}
+	if len(sets) != len(expected) {
+		t.Fatalf("%v != %v", sets, expected)
+	}
+	for i := range sets {
+		if sets[i] != expected[i] {
+			t.Fatalf("%v != %v", sets, expected)
+		}
+	}
+
+	r = strings.NewReader("copper\njade|gold\ncrystal\n")
+	_, err = DecodeKeySets(r)
+	if err == nil {
+		t.Fatalf("no error on bad line")
+	}
+	if !strings.Contains(err.Error(), "line 2") {
+		t.Fatalf("no line number: %s", err)
+	}
+}
+
+func TestEncodeKeySets(t *testing.T) {
+	sets := []KeySet{Copper | Jade, 0, Crystal, All()}
+	var buf bytes.Buffer
+	if err := EncodeKeySets(&buf, sets); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := DecodeKeySets(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != len(sets) {
+		t.Fatalf("%v != %v", out, sets)
+	}
+	for i := range sets {
+		if out[i] != sets[i] {
+			t.Fatalf("%v != %v", out, sets)
+		}
+	}
+
+	if err := EncodeKeySets(&buf, []KeySet{0x80}); err == nil {
+		t.Fatalf("no error on unknown keys")
+	}
+}
+
+type errWriter struct{}
+
+func (errWriter) Write([]byte) (int, error) {
+	return 0, io.ErrClosedPipe
+}
+
+func TestEncodeKeySetsWriteError(t *testing.T) {
+	if err := EncodeKeySets(errWriter{}, []KeySet{Copper}); err == nil {
+		t.Fatalf("no error on write error")
+	}
+}
@@ -0,0 +1,27 @@
+package bitmask
+
+import (
+	"fmt"
+	"text/template"
+)
+
+// TemplateFuncs returns template functions for KeySet:
+//
+//	hasKey KEYS NAME: true if KEYS has the key NAME
+//	keyNames KEYS: names of the keys in KEYS
+//	keyCount KEYS: number of keys in KEYS
+//
+// The functions can be used in both text/template and html/template.
+func TemplateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"hasKey": func(k KeySet, name string) (bool, error) {
+			key, ok := keyByName(name)
+			if !ok {
+				return false, fmt.Errorf("unknown key: %q", name)
+			}
+			return k&key != 0, nil
+		},
+		"keyNames": KeySet.Names,
+		"keyCount": KeySet.Count,
+	}
+}
@@ -0,0 +1,32 @@
+package bitmask
+
+import (
+	"strings"
+	"testing"
+	"text/template"
+)
+
+func TestTemplateFuncs(t *testing.T) {
+	text := `{{.Name}}: {{keyCount .Keys}} keys {{range keyNames .Keys}}[{{.}}]{{end}}` +
+		`{{if hasKey .Keys "jade"}} jade{{end}}{{if hasKey .Keys "crystal"}} crystal{{end}}`
+	tmpl, err := template.New("player").Funcs(TemplateFuncs()).Parse(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := Player{Name: "Parzival", Keys: Copper | Jade}
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, p); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "Parzival: 2 keys [copper][jade] jade"
+	if sb.String() != expected {
+		t.Fatalf("%q != %q", sb.String(), expected)
+	}
+
+	tmpl = template.Must(template.New("bad").Funcs(TemplateFuncs()).Parse(`{{hasKey .Keys "gold"}}`))
+	if err := tmpl.Execute(&sb, p); err == nil {
+		t.Fatalf("no error on unknown key")
+	}
+}
@@ -0,0 +1,32 @@
+package bitmask
+
+// KeySetView is a read only view of a KeySet
+// It has only query methods, use it to pass keys to code (such as plugins)
+// that should not modify them. KeySet is a value type so the view limits the
+// API, changing a KeySetView does not change the KeySet it was created from.
+type KeySetView KeySet
+
+// KeysView returns a read only view of the player keys
+func (p *Player) KeysView() KeySetView {
+	return KeySetView(p.Keys)
+}
+
+// Has returns true if v has key
+func (v KeySetView) Has(key KeySet) bool {
+	return KeySet(v)&key != 0
+}
+
+// Count returns the number of keys in v
+func (v KeySetView) Count() int {
+	return KeySet(v).Count()
+}
+
+// String implements the fmt.Stringer interface
+func (v KeySetView) String() string {
+	return KeySet(v).String()
+}
+
+// IsSubsetOf returns true if all keys in v are in o
+func (v KeySetView) IsSubsetOf(o KeySet) bool {
+	return KeySet(v).IsSubsetOf(o)
+}
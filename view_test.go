@@ -0,0 +1,21 @@
+package bitmask
+
+import "testing"
+
+func TestKeySetView(t *testing.T) {
+	p := Player{Name: "Parzival", Keys: Copper | Crystal}
+	v := p.KeysView()
+
+	if !v.Has(Copper) || v.Has(Jade) {
+		t.Fatalf("has: %q", v)
+	}
+	if v.Count() != 2 {
+		t.Fatalf("count: %d", v.Count())
+	}
+	if v.String() != p.Keys.String() {
+		t.Fatalf("string: %q", v)
+	}
+	if !v.IsSubsetOf(All()) || v.IsSubsetOf(Copper) {
+		t.Fatalf("subset: %q", v)
+	}
+}
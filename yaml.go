@@ -0,0 +1,32 @@
+package bitmask
+
+import "fmt"
+
+// MarshalYAML implements the yaml.Marshaler interface
+// Keys are encoded as a sequence of names
+func (k KeySet) MarshalYAML() (interface{}, error) {
+	if !k.IsValid() {
+		return nil, fmt.Errorf("unknown keys: %d", k.UnknownBits())
+	}
+	return k.names(), nil
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface (gopkg.in/yaml.v2
+// style, also supported by gopkg.in/yaml.v3)
+func (k *KeySet) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var names []string
+	if err := unmarshal(&names); err != nil {
+		return err
+	}
+
+	var keys KeySet
+	for _, name := range names {
+		key, ok := keyByName(name)
+		if !ok {
+			return fmt.Errorf("unknown key: %q", name)
+		}
+		keys |= key
+	}
+	*k = keys
+	return nil
+}
@@ -0,0 +1,53 @@
+package bitmask
+
+import (
+	"fmt"
+	"testing"
+)
+
+// yamlUnmarshal mimics the unmarshal function the YAML library passes
+func yamlUnmarshal(names []string) func(interface{}) error {
+	return func(v interface{}) error {
+		ptr, ok := v.(*[]string)
+		if !ok {
+			return fmt.Errorf("unexpected type: %T", v)
+		}
+		*ptr = names
+		return nil
+	}
+}
+
+func TestYAML(t *testing.T) {
+	v, err := (Copper | Crystal).MarshalYAML()
+	if err != nil {
+		t.Fatal(err)
+	}
+	names, ok := v.([]string)
+	if !ok || len(names) != 2 || names[0] != "copper" || names[1] != "crystal" {
+		t.Fatalf("marshal: %#v", v)
+	}
+
+	var k KeySet
+	if err := k.UnmarshalYAML(yamlUnmarshal(names)); err != nil {
+		t.Fatal(err)
+	}
+	if k != Copper|Crystal {
+		t.Fatalf("unmarshal: %q", k)
+	}
+
+	if err := k.UnmarshalYAML(yamlUnmarshal([]string{"copper", "gold"})); err == nil {
+		t.Fatalf("no error on unknown name")
+	}
+
+	if err := k.UnmarshalYAML(yamlUnmarshal([]string{"copper|jade"})); err == nil {
+		t.Fatalf("no error on joined names")
+	}
+
+	if err := k.UnmarshalYAML(yamlUnmarshal([]string{""})); err == nil {
+		t.Fatalf("no error on empty name")
+	}
+
+	if _, err := KeySet(0x80).MarshalYAML(); err == nil {
+		t.Fatalf("no error on unknown bits")
+	}
+}